@@ -1,18 +1,49 @@
 package main
 
 import (
+	"agen/config"
 	"agen/task"
+	"bufio"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var logger = log.New(os.Stderr, "agen:", log.LstdFlags)
 
+var (
+	dataPath     = ""                 // the directory holding the tasks of every store
+	storeBackend = "file"             // the backend of store
+	backendStore task.Store           // the store of the tasks of the backend
+	store        task.Store           // the store commands change tasks with
+	history      *task.History        // the history of the changes of store
+	recorded     task.Store           // backendStore, recording its history
+	operation    *task.OperationStore // recorded, keeping the changed tasks
+	cfg          = config.Default()   // the configuration of agen
+	listName     = defaultList        // the name of the list of the tasks
+	listPath     = ""                 // the directory holding the tasks of the list
+)
+
+// The name of the list stored at the root of the data directory, the other
+// lists being stored in its lists subdirectory.
+const defaultList = "default"
+
 func main() {
-	checkTasksDirOrExit()
+	args, list := takeListFlagOrExit(os.Args[1:])
+	args, dir := parseGlobalFlagsOrExit(args)
+	loadConfigOrExit()
+	setDataPathOrExit(dir)
+	setListOrExit(list, len(args) != 0 && args[0] == "newTask")
+	task.Logger = logger
+	openStoreOrExit()
 
 	newTaskCmd := flag.NewFlagSet("newTask", flag.ExitOnError)
 	newTaskCmdTitle := newTaskCmd.String("title", "", `The task title.
@@ -21,25 +52,67 @@ Length must be strictly superior to 0 and strictly inferior to 256.`)
 Length must be strictly inferior to 65536.
 This is optionnal and defaults to the empty string.`)
 	newTaskCmdPeriod := newTaskCmd.Bool("periodic", false,
-		`Indicates if the task is periodic.
+		`Indicates if the task is periodic, in which case it recurs daily.
 This is optionnal and defaults to false.`)
-	newTaskCmdPriority := newTaskCmd.String("prio", "medium",
+	newTaskCmdRepeat := newTaskCmd.String("repeat", "",
+		`The task recurrence rule, making the task periodic.
+"daily", "weekly", "weekly:mon,thu", "monthly:15" or "every:3" (days).
+This is optionnal and defaults to the rule given by -periodic.`)
+	newTaskCmdPriority := newTaskCmd.String("prio", cfg.Priority,
 		`The task priority.
 "low" for Low, "medium" for Medium and "high" for High.
-This is optionnal and defaults to Medium.`)
-	newTaskCmdStatus := newTaskCmd.String("status", "todo",
+This is optionnal and defaults to Medium, or to the priority of the
+configuration file.`)
+	newTaskCmdStatus := newTaskCmd.String("status", cfg.Status,
 		`The task status.
 "todo" for Todo, "doing" for Doing and "done" for Done.
-This is optionnal and defaults to Todo.`)
+This is optionnal and defaults to Todo, or to the status of the
+configuration file.`)
+	newTaskCmdDue := newTaskCmd.String("due", "", `The task due date.
+"2006-01-02", "2006-01-02 15:04", "today", "tomorrow", "+3d" or "+2w".
+This is optionnal and defaults to no due date.`)
+	newTaskCmdProject := newTaskCmd.String("project", "", `The task project path.
+Dot separated names, such as "infra.ci.runners".
+This is optionnal and defaults to no project.`)
+	newTaskCmdParent := newTaskCmd.String("parent", "", `The uuid (or part of it) of the parent task, making the task a subtask.
+This is optionnal and defaults to no parent.`)
+	var newTaskCmdDeps stringsFlag
+	newTaskCmd.Var(&newTaskCmdDeps, "dep", `The uuid (or part of it) of a task the task depends on, can be
+given several times.
+This is optionnal and defaults to no dependency.`)
+	var newTaskCmdTags stringsFlag
+	newTaskCmd.Var(&newTaskCmdTags, "tag", `A task tag, can be given several times.
+Tags hold no space nor comma and do not start with "+" or "-".
+This is optionnal and defaults to no tag.`)
+
+	editCmd := flag.NewFlagSet("edit", flag.ExitOnError)
+	editCmd.String("title", "", `The new task title.
+Length must be strictly superior to 0 and strictly inferior to 256.`)
+	editCmd.String("desc", "", `The new task description.
+Length must be strictly inferior to 65536.`)
+	editCmd.Bool("periodic", false,
+		`Makes the task periodic, recurring daily if it was not periodic, or not
+periodic with -periodic=false.`)
+	editCmd.String("repeat", "", `The new task recurrence rule, "none" making the task not periodic.`)
+	editCmd.String("prio", "", `The new task priority, "low", "medium" or "high".`)
+	editCmd.String("status", "", `Not editable, the status is changed with agen mark.`)
+	editCmd.String("due", "", `The new task due date, "none" removing it.`)
+	editCmd.String("project", "", `The new task project path, "none" removing it.`)
+
+	doctorCmd := flag.NewFlagSet("doctor", flag.ExitOnError)
+	doctorCmdRepair := doctorCmd.Bool("repair", false,
+		`Repairs the problems found: truncated, corrupt and duplicate files are
+moved to quarantine, misnamed files are renamed after the uuid they store.
+This is optionnal and defaults to false.`)
 
-	if len(os.Args) < 2 {
+	if len(args) < 1 {
 		fmt.Print(agenUsage())
 		os.Exit(1)
 	}
 
-	switch os.Args[1] {
+	switch args[0] {
 	case "newTask":
-		newTaskCmd.Parse(os.Args[2:])
+		newTaskCmd.Parse(args[1:])
 		if *newTaskCmdTitle == "" {
 			newTaskCmd.Usage()
 			os.Exit(1)
@@ -61,6 +134,13 @@ This is optionnal and defaults to Todo.`)
 			logAndExit(err.Error())
 		}
 		ts.SetPeriodicity(*newTaskCmdPeriod)
+		if *newTaskCmdRepeat != "" {
+			rule, err := task.ParseRecurrence(*newTaskCmdRepeat)
+			if err != nil {
+				logAndExit(err.Error())
+			}
+			ts.SetRecurrence(rule)
+		}
 		prio, err := task.ParsePriority(*newTaskCmdPriority)
 		if err != nil {
 			logAndExit(err.Error())
@@ -75,217 +155,2053 @@ This is optionnal and defaults to Todo.`)
 		if err = ts.SetStatus(status); err != nil {
 			logAndExit(err.Error())
 		}
-		if err = ts.SaveOnDisk(); err != nil {
+		if *newTaskCmdDue != "" {
+			due, err := task.ParseDue(*newTaskCmdDue, time.Now())
+			if err != nil {
+				logAndExit(err.Error())
+			}
+			ts.SetDue(due)
+		}
+		for _, tag := range newTaskCmdTags {
+			if err = ts.AddTag(tag); err != nil {
+				logAndExit(err.Error())
+			}
+		}
+		if err = ts.SetProject(*newTaskCmdProject); err != nil {
+			logAndExit(err.Error())
+		}
+		if *newTaskCmdParent != "" {
+			parent, err := task.LoadUnique(store, *newTaskCmdParent)
+			if err != nil {
+				logAndExit(*newTaskCmdParent + ": " + err.Error())
+			}
+			if err = ts.SetParent(parent.Uuid()); err != nil {
+				logAndExit(err.Error())
+			}
+		}
+		for _, prefix := range newTaskCmdDeps {
+			dep, err := task.LoadUnique(store, prefix)
+			if err != nil {
+				logAndExit(prefix + ": " + err.Error())
+			}
+			if err = ts.AddDependency(dep.Uuid()); err != nil {
+				logAndExit(err.Error())
+			}
+		}
+		if err = store.Save(ts); err != nil {
 			logAndExit(err.Error())
 		}
 	case "list":
-		listArgs := os.Args[2:]
+		listArgs := args[1:]
 		if len(listArgs) != 0 {
 			if checkForHelpAndPrintUsage(listArgs, listUsage()) {
 				os.Exit(0)
 			}
 		}
-		tasks, err := task.LoadTasks()
-		if err != nil {
-			logAndExit(err.Error())
-		}
-		tasks, err = task.FilterTasks(tasks, listArgs)
-		if err != nil {
+		if err := handleList(listArgs); err != nil {
 			logAndExit(err.Error())
 		}
-		for _, task := range tasks {
-			fmt.Printf("> %s\n", task.Display())
-		}
 	case "mark":
-		if len(os.Args) < 3 {
-			logAndExit("no specific mark given")
-		}
-		if checkForHelpAndPrintUsage(os.Args[2:], markUsage()) {
+		if checkForHelpAndPrintUsage(args[1:], markUsage()) {
 			os.Exit(0)
 		}
-		switch os.Args[2] {
+		var force, cascade bool
+		args, force = takeFlag(args, "force")
+		args, cascade = takeFlag(args, "cascade")
+		if len(args) < 2 {
+			logAndExit("no specific mark given")
+		}
+		switch args[1] {
 		case "todo", "doing", "done":
-			if len(os.Args[2:]) < 2 {
+			if len(args[1:]) < 2 {
 				os.Exit(0)
 			}
-			if err := handleStatusMark(os.Args[2], os.Args[3:]); err != nil {
+			err := handleStatusMark(args[1], args[2:], force, cascade)
+			if err != nil {
 				logAndExit(err.Error())
 			}
 		case "low", "medium", "high":
-			if len(os.Args[2:]) < 2 {
+			if len(args[1:]) < 2 {
 				os.Exit(0)
 			}
-			if err := handlePriorityMark(os.Args[2], os.Args[3:]); err != nil {
+			if err := handlePriorityMark(args[1], args[2:]); err != nil {
 				logAndExit(err.Error())
 			}
 		default:
-			logger.Println("unkown mark: " + os.Args[2])
+			if strings.HasPrefix(args[1], "due:") {
+				if len(args[1:]) < 2 {
+					os.Exit(0)
+				}
+				due := strings.TrimPrefix(args[1], "due:")
+				if err := handleDueMark(due, args[2:]); err != nil {
+					logAndExit(err.Error())
+				}
+				break
+			}
+			if strings.HasPrefix(args[1], "repeat:") {
+				if len(args[1:]) < 2 {
+					os.Exit(0)
+				}
+				rule := strings.TrimPrefix(args[1], "repeat:")
+				if err := handleRepeatMark(rule, args[2:]); err != nil {
+					logAndExit(err.Error())
+				}
+				break
+			}
+			if strings.HasPrefix(args[1], "dep:") ||
+				strings.HasPrefix(args[1], "nodep:") {
+				if len(args[1:]) < 2 {
+					os.Exit(0)
+				}
+				kind, prefix, _ := strings.Cut(args[1], ":")
+				err := handleDependencyMark(prefix, kind == "dep", args[2:])
+				if err != nil {
+					logAndExit(err.Error())
+				}
+				break
+			}
+			if strings.HasPrefix(args[1], "parent:") {
+				if len(args[1:]) < 2 {
+					os.Exit(0)
+				}
+				parent := strings.TrimPrefix(args[1], "parent:")
+				if err := handleParentMark(parent, args[2:]); err != nil {
+					logAndExit(err.Error())
+				}
+				break
+			}
+			if strings.HasPrefix(args[1], "project:") {
+				if len(args[1:]) < 2 {
+					os.Exit(0)
+				}
+				project := strings.TrimPrefix(args[1], "project:")
+				if err := handleProjectMark(project, args[2:]); err != nil {
+					logAndExit(err.Error())
+				}
+				break
+			}
+			if strings.HasPrefix(args[1], "+") ||
+				strings.HasPrefix(args[1], "-") {
+				if len(args[1:]) < 2 {
+					os.Exit(0)
+				}
+				if err := handleTagMark(args[1], args[2:]); err != nil {
+					logAndExit(err.Error())
+				}
+				break
+			}
+			logger.Println("unkown mark: " + args[1])
 			fmt.Println(markUsage())
 			os.Exit(1)
 		}
 	case "remove":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			os.Exit(0)
 		}
-		removeArgs := os.Args[2:]
+		removeArgs := args[1:]
 		if checkForHelpAndPrintUsage(removeArgs, removeUsage()) {
 			os.Exit(0)
 		}
-		if err := handleRemove(removeArgs); err != nil {
+		removeArgs, cascade := takeFlag(removeArgs, "cascade")
+		removeArgs, reparent := takeFlag(removeArgs, "reparent")
+		if cascade && reparent {
+			logAndExit("--cascade and --reparent can not be both given")
+		}
+		openTrashOrExit()
+		if err := handleRemove(removeArgs, cascade, reparent); err != nil {
+			logAndExit(err.Error())
+		}
+	case "migrate":
+		if checkForHelpAndPrintUsage(args[1:], migrateUsage()) {
+			os.Exit(0)
+		}
+		if err := handleMigrate(); err != nil {
+			logAndExit(err.Error())
+		}
+	case "store":
+		storeArgs := args[1:]
+		if checkForHelpAndPrintUsage(storeArgs, storeUsage()) {
+			os.Exit(0)
+		}
+		if len(storeArgs) == 0 {
+			fmt.Printf("%s store in %s\n", storeBackend, dataPath)
+			break
+		}
+		if storeArgs[0] == "convert" && len(storeArgs) == 3 {
+			err := handleStoreConvert(storeArgs[1], storeArgs[2])
+			if err != nil {
+				logAndExit(err.Error())
+			}
+			break
+		}
+		if storeArgs[0] == "compact" && len(storeArgs) == 1 {
+			if err := handleStoreCompact(); err != nil {
+				logAndExit(err.Error())
+			}
+			break
+		}
+		logger.Println("invalid store arguments")
+		fmt.Println(storeUsage())
+		os.Exit(1)
+	case "tags":
+		if checkForHelpAndPrintUsage(args[1:], tagsUsage()) {
+			os.Exit(0)
+		}
+		if err := handleTags(args[1:]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "projects":
+		if checkForHelpAndPrintUsage(args[1:], projectsUsage()) {
+			os.Exit(0)
+		}
+		if err := handleProjects(args[1:]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "search":
+		if len(args) < 2 ||
+			checkForHelpAndPrintUsage(args[1:], searchUsage()) {
+			fmt.Println(searchUsage())
+			os.Exit(0)
+		}
+		if err := handleSearch(args[1:]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "undo", "redo":
+		if checkForHelpAndPrintUsage(args[1:], undoUsage()) {
+			os.Exit(0)
+		}
+		openTrashOrExit()
+		if err := handleUndo(args[0] == "redo"); err != nil {
+			logAndExit(err.Error())
+		}
+	case "trash":
+		if checkForHelpAndPrintUsage(args[1:], trashUsage()) {
+			os.Exit(0)
+		}
+		openTrashOrExit()
+		if err := handleTrash(args[1:]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "archive":
+		if checkForHelpAndPrintUsage(args[1:], archiveUsage()) {
+			os.Exit(0)
+		}
+		if err := handleArchive(args[1:]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "unarchive":
+		if len(args) < 2 ||
+			checkForHelpAndPrintUsage(args[1:], archiveUsage()) {
+			fmt.Println(archiveUsage())
+			os.Exit(0)
+		}
+		if err := handleUnarchive(args[1:]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "log":
+		if checkForHelpAndPrintUsage(args[1:], logUsage()) {
+			os.Exit(0)
+		}
+		if err := handleLog(args[1:]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "show":
+		if len(args) != 2 ||
+			checkForHelpAndPrintUsage(args[1:], showUsage()) {
+			fmt.Println(showUsage())
+			os.Exit(0)
+		}
+		if err := handleShow(args[1]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "lists":
+		if checkForHelpAndPrintUsage(args[1:], listsUsage()) {
+			os.Exit(0)
+		}
+		if err := handleLists(); err != nil {
+			logAndExit(err.Error())
+		}
+	case "move":
+		if checkForHelpAndPrintUsage(args[1:], moveUsage()) {
+			os.Exit(0)
+		}
+		if len(args) < 2 {
+			logger.Println("no list given")
+			fmt.Println(moveUsage())
+			os.Exit(1)
+		}
+		if err := handleMove(args[1], args[2:]); err != nil {
+			logAndExit(err.Error())
+		}
+	case "edit":
+		editCmd.Usage = func() { fmt.Println(editUsage()) }
+		if len(args) < 2 {
+			editCmd.Usage()
+			os.Exit(1)
+		}
+		if checkForHelpAndPrintUsage(args[1:2], editUsage()) {
+			os.Exit(0)
+		}
+		editCmd.Parse(args[2:])
+		if editCmd.NArg() != 0 {
+			editCmd.Usage()
+			os.Exit(1)
+		}
+		if err := handleEdit(args[1], editCmd); err != nil {
+			logAndExit(err.Error())
+		}
+	case "doctor":
+		doctorCmd.Usage = func() { fmt.Println(doctorUsage()) }
+		doctorCmd.Parse(args[1:])
+		if err := handleDoctor(*doctorCmdRepair); err != nil {
 			logAndExit(err.Error())
 		}
 	default:
-		logAndExit("unknown subcommand: " + os.Args[1])
+		logAndExit("unknown subcommand: " + args[0])
 	}
+	recordOperation()
 }
 
 // Prints the given message on the logger and exits the program with exit status
-// code 1
+// code 1. The changes made before are recorded so that they can be undone.
 func logAndExit(msg string) {
 	logger.Println(msg)
+	recordOperation()
 	os.Exit(1)
 }
 
-// Checks that the directory `$HOME/.agen/tasks` exists. Exits with status code
-// 1 if it does not exist. Also sets the tasks save path
-func checkTasksDirOrExit() {
-	homePath := os.Getenv("HOME")
-	if homePath == "" {
-		logAndExit("$HOME not set")
+// Records the changes of the tasks made by the command as an operation that
+// can be undone, if some tasks changed.
+func recordOperation() {
+	if operation == nil {
+		return
 	}
-	task.TasksPath = homePath + "/.agen/tasks"
-	f, err := os.Open(task.TasksPath)
-	if err != nil {
-		logAndExit(err.Error())
+	op, err := operation.Operation(strings.Join(os.Args[1:], " "))
+	if err == nil && op != nil {
+		var journal *task.UndoJournal
+		if journal, err = task.OpenUndoJournal(undoPath(listPath)); err == nil {
+			err = journal.Record(op)
+		}
 	}
-	fi, err := f.Stat()
 	if err != nil {
-		logAndExit(err.Error())
-	}
-	if !fi.IsDir() {
-		logAndExit("tasks directory missing")
+		logger.Println("could not record the changes to undo: " + err.Error())
 	}
 }
 
-// Handle for status marking, the given status must be either "todo", "doing" or
-// "done", the string slice can be empty and contains the uuids of part of it
-// of the tasks to mark. Returns a non-nil error if the given tasks were marked.
-func handleStatusMark(status string, args []string) error {
-	stat, err := task.ParseStatus(status)
+// Returns the path of the undo journal of the tasks of given root directory.
+func undoPath(root string) string {
+	return filepath.Join(root, "undo")
+}
+
+// Undoes the latest change of the tasks not undone yet if redo is false, and
+// redoes the latest undone change otherwise, and prints the command of the
+// change.
+func handleUndo(redo bool) error {
+	journal, err := task.OpenUndoJournal(undoPath(listPath))
 	if err != nil {
 		return err
 	}
-	for _, uuid := range args {
-		existsAndUnique, err := task.ExistsAndIsUnique(uuid)
+	var op *task.Operation
+	verb := "undid"
+	if redo {
+		op, err = journal.RedoLast(recorded)
+		verb = "redid"
+	} else {
+		op, err = journal.UndoLast(recorded)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s \"%s\" of %s (%d tasks)\n", verb, op.Command,
+		op.Time.Format("2006-01-02 15:04"), len(op.Tasks))
+	return nil
+}
+
+// Runs the trash subcommand of given arguments: lists the trashed tasks without
+// arguments or with "list", restores the trashed tasks of given uuids with
+// "restore" and removes for good the trashed tasks with "empty".
+func handleTrash(args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "list":
+		if len(args) != 1 {
+			return errors.New("trash list takes no arguments")
+		}
+		tasks, err := task.DefaultTrash.Tasks()
 		if err != nil {
 			return err
 		}
-		if existsAndUnique {
-			ts, err := task.LoadTask(uuid)
+		for _, ts := range tasks {
+			fmt.Printf("%s %s\n", ts.Deleted().Format("2006-01-02 15:04"),
+				display(ts))
+		}
+	case "restore":
+		if len(args) < 2 {
+			return errors.New("no task to restore given")
+		}
+		for _, prefix := range args[1:] {
+			ts, err := task.DefaultTrash.Restore(prefix, store)
 			if err != nil {
-				return err
-			}
-			if err = ts.SetStatus(stat); err != nil {
-				return err
+				return fmt.Errorf("%s: %w", prefix, err)
 			}
-			if err = ts.SaveOnDisk(); err != nil {
+			fmt.Printf("restored \"%s\"\n", ts.Title())
+		}
+	case "empty":
+		rest, olderThan, err := takeValueFlag(args[1:], "older-than")
+		if err != nil {
+			return err
+		}
+		if len(rest) != 0 {
+			return errors.New("trash empty takes no arguments but --older-than")
+		}
+		before := time.Time{}
+		if olderThan != "" {
+			if before, err = parseSince(olderThan, time.Now()); err != nil {
 				return err
 			}
-		} else {
-			return errors.New("uuid prefix not unique")
 		}
+		removed, err := task.DefaultTrash.Empty(before)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("removed %d tasks for good\n", removed)
+	default:
+		return errors.New("unknown trash subcommand: " + args[0])
 	}
 	return nil
 }
 
-// Handle for priority marking, the given priority must be either "low",
-// "medium" or "high", the string slice can be empty and contains the name of
-// the tasks to mark. Returns a non-nil error if the given task names were
-// marked.
-func handlePriorityMark(priority string, args []string) error {
-	prio, err := task.ParsePriority(priority)
+// Returns the arguments following the global flags given before the
+// subcommand, and the directory given by --dir, empty if not given. Exits with
+// status code 1 if a global flag is invalid.
+func parseGlobalFlagsOrExit(args []string) ([]string, string) {
+	dir := ""
+	for len(args) != 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if name != "dir" {
+			if checkForHelpAndPrintUsage(args[:1], agenUsage()) {
+				os.Exit(0)
+			}
+			logAndExit("unknown global flag: " + args[0])
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				logAndExit("missing value of global flag: " + args[0])
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		if value == "" {
+			logAndExit("empty value of global flag: " + args[0])
+		}
+		dir = value
+		args = args[1:]
+	}
+	return args, dir
+}
+
+// Loads the configuration file, if it exists. Exits with status code 1 if it
+// can not be read or is invalid.
+func loadConfigOrExit() {
+	path, err := config.DefaultPath()
 	if err != nil {
-		return err
+		// without $HOME the defaults are used, the data directory must then
+		// be given explicitly
+		return
 	}
-	for _, name := range args {
-		existsAndUnique, err := task.ExistsAndIsUnique(name)
-		if err != nil {
-			return err
+	if cfg, err = config.Load(path); err != nil {
+		logAndExit(err.Error())
+	}
+}
+
+// Returns the data directory: the given directory if not empty, otherwise
+// $AGEN_DIR, the directory of the configuration file, $HOME/.agen if it
+// exists, $XDG_DATA_HOME/agen or $HOME/.local/share/agen, in this order.
+func dataDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+	if dir = os.Getenv("AGEN_DIR"); dir != "" {
+		return dir, nil
+	}
+	if cfg.Dir != "" {
+		return cfg.Dir, nil
+	}
+	home := os.Getenv("HOME")
+	if home != "" {
+		legacy := filepath.Join(home, ".agen")
+		if fi, err := os.Stat(legacy); err == nil && fi.IsDir() {
+			return legacy, nil
 		}
-		if existsAndUnique {
-			ts, err := task.LoadTask(name)
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "agen"), nil
+	}
+	if home == "" {
+		return "", errors.New("$HOME not set")
+	}
+	return filepath.Join(home, ".local", "share", "agen"), nil
+}
+
+// Sets the data directory from the given directory, empty if not given. Exits
+// with status code 1 if there is no data directory.
+func setDataPathOrExit(dir string) {
+	var err error
+	if dataPath, err = dataDir(dir); err != nil {
+		logAndExit(err.Error())
+	}
+}
+
+// Returns the arguments without the --in flag and the name of the list it
+// gives, empty if not given. The flag can be given anywhere in the arguments.
+// Exits with status code 1 if the flag has no value.
+func takeListFlagOrExit(args []string) ([]string, string) {
+	args, list, err := takeValueFlag(args, "in")
+	if err != nil {
+		logAndExit(err.Error())
+	}
+	return args, list
+}
+
+// Returns the directory holding the tasks of the list of given name.
+func listRoot(name string) string {
+	if name == defaultList {
+		return dataPath
+	}
+	return filepath.Join(dataPath, "lists", name)
+}
+
+// Returns true if the list of given name exists. The default list always
+// exists.
+func listExists(name string) bool {
+	if name == defaultList {
+		return true
+	}
+	fi, err := os.Stat(listRoot(name))
+	return err == nil && fi.IsDir()
+}
+
+// Sets the list of the tasks to the list of given name, or to the list of the
+// configuration file if the name is empty, and sets the tasks save path in it.
+// Exits with status code 1 if the list does not exist, unless create is true.
+func setListOrExit(name string, create bool) {
+	if name == "" {
+		name = cfg.List
+	}
+	if name == "" {
+		name = defaultList
+	}
+	if err := task.CheckListName(name); err != nil {
+		logAndExit(err.Error())
+	}
+	if !create && !listExists(name) {
+		logAndExit("list " + name + " does not exist, create it with " +
+			"agen newTask --in " + name)
+	}
+	listName = name
+	listPath = listRoot(name)
+	task.TasksPath = filepath.Join(listPath, "tasks")
+}
+
+// A stringsFlag is a flag that can be given several times, holding every
+// value given.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// Returns the given task in the display format of the configuration.
+func display(ts *task.Task) string {
+	if cfg.Format == "short" {
+		return ts.ShortDisplay()
+	}
+	return ts.Display()
+}
+
+// Applies the given change to the tasks denoted by the given uuids or part of
+// it, and saves them. Every uuid must denote exactly one task, otherwise no
+// task is changed. A task denoted several times is changed once. With a store
+// supporting batches, the tasks are saved at once.
+func markTasks(args []string, change func(*task.Task) error) error {
+	return task.Batch(store, func(s task.Store) error {
+		var tasks []*task.Task
+		seen := make(map[string]bool)
+		for _, uuid := range args {
+			ts, err := task.LoadUnique(s, uuid)
 			if err != nil {
-				return err
+				return fmt.Errorf("%s: %w", uuid, err)
 			}
-			if err = ts.SetPriority(prio); err != nil {
+			if !seen[ts.Uuid()] {
+				seen[ts.Uuid()] = true
+				tasks = append(tasks, ts)
+			}
+		}
+		for _, ts := range tasks {
+			if err := change(ts); err != nil {
 				return err
 			}
-			if err = ts.SaveOnDisk(); err != nil {
+			ts.Touch()
+			if err := s.Save(ts); err != nil {
 				return err
 			}
-		} else {
-			return errors.New("uuid prefix not unique")
 		}
+		return nil
+	})
+}
 
+// Opens the store of the backend given by the AGEN_STORE environment variable,
+// or by the configuration file if it is not set, and makes it the default
+// store. Exits with status code 1 if the store can not be opened.
+func openStoreOrExit() {
+	storeBackend = cfg.Store
+	if backend := os.Getenv("AGEN_STORE"); backend != "" {
+		storeBackend = backend
 	}
-	return nil
+	var err error
+	backendStore, err = openStore(listPath, storeBackend)
+	if err != nil {
+		logAndExit(err.Error())
+	}
+	history = task.NewHistory(historyPath(listPath))
+	recorded = task.NewHistoryStore(backendStore, history)
+	operation = task.NewOperationStore(recorded)
+	store = operation
+	task.DefaultStore = store
 }
 
-// Removes the tasks denoted by the given uuids or part of it. If something
-// wrong happens, returns an error. The args slice can be empty.
-func handleRemove(args []string) error {
-	for _, uuid := range args {
-		if err := task.Remove(uuid); err != nil {
-			return err
-		}
+// Opens the trash of the list in use as task.DefaultTrash, so that the removed
+// tasks are moved to it. Exits with status code 1 if it can not be opened.
+func openTrashOrExit() {
+	s, err := openStore(trashPath(listPath), storeBackend)
+	if err != nil {
+		logAndExit(err.Error())
 	}
-	return nil
+	task.DefaultTrash = task.NewTrash(s)
 }
 
-// checks every element of args for equality with "-h", "--help" or "help" and
-// if one is found that equals one of the strings, prints usage to the log
-// and returns true
-func checkForHelpAndPrintUsage(args []string, usage string) bool {
-	for _, arg := range args {
-		if arg == "-h" || arg == "--help" || arg == "help" || arg == "-help" {
-			fmt.Println(usage)
-			return true
-		}
-	}
-	return false
+// Returns the directory of the trash of the tasks of given root directory.
+func trashPath(root string) string {
+	return filepath.Join(root, "trash")
 }
 
-func agenUsage() string {
-	return `Usage of agen:
-  agen newTask: create a new task
-  agen list: list tasks
-  agen mark: mark a task as done or as of high priority
-`
+// Returns the directory of the archive of the tasks of given root directory.
+func archivePath(root string) string {
+	return filepath.Join(root, "archive")
 }
 
-func listUsage() string {
-	return `Usage of list:
-  agen list [filter ...]
-where filter is one of the following:
-  status: todo, doing, done
-  priority: low, medium, high
+// Returns the path of the history file of the tasks of given root directory.
+func historyPath(root string) string {
+	return filepath.Join(root, "history")
+}
 
-When several filters from the same category ("status" or "priority") are given,
-they form a union filter, meaning that tasks that satisfy one of the given
-filters could be listed (if not filtered out by the other category). If filters
-from different categories are given, they form an intersection filter, meaning
-that a task must have a status in the status filters and a priority in the
-priority filters.
+// Opens the store of given backend, "file", "sqlite" or "journal", in the
+// given list directory, creating the directory if it does not exist.
+func openStore(root, backend string) (task.Store, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	switch backend {
+	case "file":
+		path := filepath.Join(root, "tasks")
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+		return task.NewFileStore(path), nil
+	case "sqlite":
+		return task.OpenSQLiteStore(filepath.Join(root, "tasks.db"))
+	case "journal":
+		return task.OpenJournalStore(filepath.Join(root, "journal"))
+	default:
+		return nil, errors.New("unknown store backend: " + backend)
+	}
+}
 
-Examples:
-  - to list all done tasks: agen list done
-  - to list all done or todo tasks: agen list done todo
-  - to list all todo tasks that have priority high: agen list todo high`
+// Closes the given store if it holds resources to release.
+func closeStore(s task.Store) {
+	if closer, ok := s.(io.Closer); ok {
+		closer.Close()
+	}
 }
 
-func markUsage() string {
+// Prints the given question followed by "[y/N]" and returns true if the answer
+// read on the standard input is yes.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// Prints the tasks matching the given query, sorted by the keys of the --sort
+// flag or by task.DefaultSort. With the --group-by flag, the tasks are printed
+// in groups, each with a header giving its number of tasks. With the --tree
+// flag, subtasks are printed indented under their parent, with the progress
+// of the parents. The --format flag prints the tasks in the given display
+// format or exports them, see task.Export, and the --template flag prints each
+// task with the given template, see task.ParseTemplate.
+func handleList(args []string) error {
+	args, tree := takeFlag(args, "tree")
+	args, archived := takeFlag(args, "archived")
+	args, sortSpec, err := takeValueFlag(args, "sort")
+	if err != nil {
+		return err
+	}
+	args, groupBy, err := takeValueFlag(args, "group-by")
+	if err != nil {
+		return err
+	}
+	args, format, err := takeValueFlag(args, "format")
+	if err != nil {
+		return err
+	}
+	args, tmplText, err := takeValueFlag(args, "template")
+	if err != nil {
+		return err
+	}
+	if sortSpec == "" {
+		sortSpec = task.DefaultSort
+	}
+	keys, err := task.ParseSortKeys(sortSpec)
+	if err != nil {
+		return err
+	}
+	printGroup := func(tasks []*task.Task) error {
+		printTasks(tasks, tree)
+		return nil
+	}
+	switch format {
+	case "", "text", "short":
+		if format != "" {
+			cfg.Format = format
+		}
+	case "json", "ndjson", "csv", "tsv":
+		if tree || groupBy != "" || tmplText != "" {
+			return errors.New("--format " + format +
+				" can not be combined with --tree, --group-by or --template")
+		}
+		printGroup = func(tasks []*task.Task) error {
+			return task.Export(os.Stdout, tasks, format)
+		}
+	default:
+		return errors.New("format must be text, short, json, ndjson, csv or tsv")
+	}
+	if tmplText != "" {
+		if tree {
+			return errors.New("--template can not be combined with --tree")
+		}
+		tmpl, err := task.ParseTemplate(tmplText)
+		if err != nil {
+			return err
+		}
+		printGroup = func(tasks []*task.Task) error {
+			return task.ExecuteTemplate(os.Stdout, tasks, tmpl)
+		}
+	}
+	source := store
+	if archived {
+		archive, err := openStore(archivePath(listPath), storeBackend)
+		if err != nil {
+			return err
+		}
+		defer closeStore(archive)
+		source = archive
+	} else if err = autoArchive(); err != nil {
+		return err
+	}
+	tasks, err := task.LoadQuery(source, strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	task.SortTasks(tasks, keys)
+	if groupBy == "" {
+		return printGroup(tasks)
+	}
+	groups, err := task.GroupTasks(tasks, groupBy)
+	if err != nil {
+		return err
+	}
+	for i, group := range groups {
+		if i != 0 {
+			fmt.Println()
+		}
+		name := group.Name
+		if name == "" {
+			name = "(no " + groupBy + ")"
+		}
+		fmt.Printf("%s: %s (%d)\n", groupBy, name, len(group.Tasks))
+		if err = printGroup(group.Tasks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Archives the done tasks completed more than the number of days of the
+// archive_after key of the configuration ago, if it is set.
+func autoArchive() error {
+	if cfg.ArchiveAfter == 0 {
+		return nil
+	}
+	archive, err := openStore(archivePath(listPath), storeBackend)
+	if err != nil {
+		return err
+	}
+	defer closeStore(archive)
+	_, err = task.Archive(recorded, archive,
+		time.Now().AddDate(0, 0, -cfg.ArchiveAfter))
+	return err
+}
+
+// Moves the done tasks to the archive of the list, only those completed
+// before the date of the --before flag if given, and prints their number.
+// Archiving is not undone by undo, the archived tasks being unarchived with
+// unarchive instead.
+func handleArchive(args []string) error {
+	args, beforeFlag, err := takeValueFlag(args, "before")
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("archive takes no arguments but --before")
+	}
+	before := time.Time{}
+	if beforeFlag != "" {
+		if before, err = task.ParseDue(beforeFlag, time.Now()); err != nil {
+			return fmt.Errorf("--before: %w", err)
+		}
+	}
+	archive, err := openStore(archivePath(listPath), storeBackend)
+	if err != nil {
+		return err
+	}
+	defer closeStore(archive)
+	archived, err := task.Archive(recorded, archive, before)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("archived %d tasks\n", len(archived))
+	return nil
+}
+
+// Moves the archived tasks denoted by the given uuids or part of it back to
+// the list.
+func handleUnarchive(args []string) error {
+	archive, err := openStore(archivePath(listPath), storeBackend)
+	if err != nil {
+		return err
+	}
+	defer closeStore(archive)
+	for _, prefix := range args {
+		ts, err := task.Unarchive(archive, recorded, prefix)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+		fmt.Printf("unarchived \"%s\"\n", ts.Title())
+	}
+	return nil
+}
+
+// Prints the given tasks in their order. If tree is true, subtasks are printed
+// indented under their parent, with the progress of the parents.
+func printTasks(tasks []*task.Task, tree bool) {
+	if !tree {
+		for _, ts := range tasks {
+			fmt.Printf("> %s\n", display(ts))
+		}
+		return
+	}
+	h := task.NewHierarchy(tasks)
+	var printTree func(tasks []*task.Task, depth int)
+	printTree = func(tasks []*task.Task, depth int) {
+		for _, ts := range tasks {
+			progress := ""
+			if done, total := h.Progress(ts.Uuid()); total != 0 {
+				progress = fmt.Sprintf(" [%d/%d done]", done, total)
+			}
+			fmt.Printf("%s> %s%s\n", strings.Repeat("  ", depth),
+				display(ts), progress)
+			printTree(h.Children(ts.Uuid()), depth+1)
+		}
+	}
+	printTree(h.Roots(), 0)
+}
+
+// The number of lines of the description of a task printed under it by
+// search at most.
+const searchDescLines = 3
+
+// Prints the tasks whose title or description match every given term, the
+// most relevant first, the title hits ranking above the description hits.
+// The terms are regular expressions with the --regex flag, and the searched
+// tasks those matching the query of the --where flag. The matches are
+// highlighted when printing to a terminal, and the description lines holding
+// matches are printed under their task.
+func handleSearch(args []string) error {
+	args, regex := takeFlag(args, "regex")
+	args, where, err := takeValueFlag(args, "where")
+	if err != nil {
+		return err
+	}
+	tasks, err := task.LoadQuery(store, where)
+	if err != nil {
+		return err
+	}
+	results, err := task.Search(tasks, args, regex)
+	if err != nil {
+		return err
+	}
+	open, close := "", ""
+	if isTerminal(os.Stdout) {
+		open, close = "\x1b[1;33m", "\x1b[0m"
+	}
+	for _, res := range results {
+		line := display(res.Task)
+		// the title follows the status in the displayed task
+		if status, rest, ok := strings.Cut(line, "] "); ok {
+			title := res.Task.Title()
+			rest = task.Highlight(title, res.TitleMatches, open, close) +
+				strings.TrimPrefix(rest, title)
+			line = status + "] " + rest
+		}
+		fmt.Printf("> %s\n", line)
+		printDescMatches(res.Task.Description(), res.DescMatches, open, close)
+	}
+	return nil
+}
+
+// Prints indented the first searchDescLines lines of the given description
+// holding some of the given sorted matches, highlighted with open and close.
+func printDescMatches(desc string, matches [][2]int, open, close string) {
+	printed := 0
+	start := 0
+	for _, line := range strings.SplitAfter(desc, "\n") {
+		end := start + len(line)
+		var inLine [][2]int
+		for _, m := range matches {
+			if m[0] < end && m[1] > start {
+				inLine = append(inLine, [2]int{max(m[0], start) - start,
+					min(m[1], end) - start})
+			}
+		}
+		if len(inLine) != 0 {
+			if printed == searchDescLines {
+				fmt.Println("    ...")
+				return
+			}
+			line = task.Highlight(line, inLine, open, close)
+			fmt.Printf("    %s\n", strings.TrimRight(line, "\n"))
+			printed++
+		}
+		start = end
+	}
+}
+
+// Changes the fields of the task of given uuid prefix to the values of the
+// flags set in the given flag set, or to the fields edited in $EDITOR if no
+// flag is set.
+func handleEdit(prefix string, flags *flag.FlagSet) error {
+	ts, err := task.LoadUnique(store, prefix)
+	if err != nil {
+		return fmt.Errorf("%s: %w", prefix, err)
+	}
+	set := 0
+	flags.Visit(func(*flag.Flag) { set++ })
+	if set == 0 {
+		if err = editInEditor(ts); err != nil {
+			return err
+		}
+		ts.Touch()
+		return store.Save(ts)
+	}
+	var errs []error
+	flags.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		var err error
+		switch f.Name {
+		case "title":
+			err = ts.SetTitle(value)
+		case "desc":
+			err = ts.SetDescription(value)
+		case "periodic":
+			ts.SetPeriodicity(value == "true")
+		case "repeat":
+			var rule task.Recurrence
+			if rule, err = task.ParseRecurrence(value); err == nil {
+				ts.SetRecurrence(rule)
+			}
+		case "prio":
+			var prio byte
+			if prio, err = task.ParsePriority(value); err == nil {
+				err = ts.SetPriority(prio)
+			}
+		case "status":
+			err = fmt.Errorf("%w: agen mark %s %s", task.ErrStatusNotEditable,
+				value, prefix)
+		case "due":
+			due := time.Time{}
+			if value != "none" {
+				due, err = task.ParseDue(value, time.Now())
+			}
+			if err == nil {
+				ts.SetDue(due)
+			}
+		case "project":
+			if value == "none" {
+				value = ""
+			}
+			err = ts.SetProject(value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+		}
+	})
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	ts.Touch()
+	return store.Save(ts)
+}
+
+// Opens $EDITOR, or vi, on a temporary file holding the edit text of the
+// given task, and edits the task with the text of the file once the editor
+// exits. The file is removed unless the edited text is invalid, so that it is
+// not lost.
+func editInEditor(ts *task.Task) error {
+	f, err := os.CreateTemp("", "agen-*.txt")
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	_, err = f.WriteString(ts.EditText())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("editor: %w", err)
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	if err = ts.Edit(string(text), time.Now()); err != nil {
+		return fmt.Errorf("%w, the edited file is kept at %s", err, path)
+	}
+	return os.Remove(path)
+}
+
+// Prints the history of the task of given uuid prefix, or of every task if no
+// prefix is given, from the oldest change to the latest. With the --since
+// flag, only the changes made since the given time are printed.
+func handleLog(args []string) error {
+	args, sinceFlag, err := takeValueFlag(args, "since")
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return errors.New("log takes at most one task")
+	}
+	since := time.Time{}
+	if sinceFlag != "" {
+		if since, err = parseSince(sinceFlag, time.Now()); err != nil {
+			return err
+		}
+	}
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+	entries, err := history.Entries(prefix, time.Time{})
+	if err != nil {
+		return err
+	}
+	if prefix != "" {
+		// the task may have been removed, so it is looked up in its history
+		uuids := make(map[string]bool)
+		for _, entry := range entries {
+			uuids[entry.Uuid] = true
+		}
+		switch {
+		case len(uuids) > 1:
+			return fmt.Errorf("%s: %w", prefix, task.ErrUuidNotUnique)
+		case len(uuids) == 0:
+			return fmt.Errorf("%s: %w", prefix, task.ErrTaskNotFound)
+		}
+	}
+	for _, entry := range entries {
+		if !entry.Time.Before(since) {
+			fmt.Printf("%s %s %q %s\n", entry.Time.Format("2006-01-02 15:04"),
+				entry.Uuid[:min(task.ShortUuidLength, len(entry.Uuid))],
+				entry.Title, describeChange(entry))
+		}
+	}
+	return nil
+}
+
+// The number of characters of the values of the changes printed at most.
+const changeValueMaxLength = 60
+
+// Returns the change of the given history entry with the user who made it,
+// such as "status: "todo" -> "doing" by user@host".
+func describeChange(entry task.HistoryEntry) string {
+	// quotes a value, shortening the long ones
+	value := func(v string) string {
+		if v == "" {
+			return "(none)"
+		}
+		if runes := []rune(v); len(runes) > changeValueMaxLength {
+			v = string(runes[:changeValueMaxLength]) + "..."
+		}
+		return fmt.Sprintf("%q", v)
+	}
+	change := ""
+	switch entry.Action {
+	case task.ActionCreate:
+		change = "created"
+	case task.ActionRemove:
+		change = "removed"
+	default:
+		change = fmt.Sprintf("%s: %s -> %s", entry.Field, value(entry.Old),
+			value(entry.New))
+	}
+	return change + " by " + entry.Actor
+}
+
+// Parses the time denoted by the given string relatively to now: a duration
+// before now such as "2d" (days), "1w" (weeks) or "3h30m", or a date of
+// task.ParseDue.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	for suffix, days := range map[string]int{"d": 1, "w": 7} {
+		n, err := strconv.Atoi(strings.TrimSuffix(since, suffix))
+		if strings.HasSuffix(since, suffix) && err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n*days), nil
+		}
+	}
+	if d, err := time.ParseDuration(since); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	t, err := task.ParseDue(since, now)
+	if err != nil {
+		return time.Time{}, errors.New("not a valid since time: " + since)
+	}
+	return t, nil
+}
+
+// The width the descriptions printed by show are wrapped to when the output is
+// not a terminal or its width is unknown.
+const defaultWrapWidth = 80
+
+// Prints every field of the task of given uuid prefix, with its relations to
+// the other tasks, its description wrapped to the width of the terminal and
+// its history.
+func handleShow(prefix string) error {
+	ts, err := task.LoadUnique(store, prefix)
+	if err != nil {
+		return fmt.Errorf("%s: %w", prefix, err)
+	}
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+	graph := task.NewDependencyGraph(tasks)
+	h := task.NewHierarchy(tasks)
+	// prints the value after the field name, or aligned with the values
+	// above if the name is empty
+	field := func(name, value string) {
+		if name != "" {
+			name += ":"
+		}
+		fmt.Printf("%-12s %s\n", name, value)
+	}
+	// prints the tasks of given uuids, the first after the field name
+	relations := func(name string, uuids []string) {
+		for _, uuid := range uuids {
+			value := uuid + " (missing)"
+			if related := graph.Task(uuid); related != nil {
+				value = related.ShortDisplay()
+			}
+			field(name, value)
+			name = ""
+		}
+	}
+	field("Title", ts.Title())
+	field("UUID", ts.Uuid())
+	field("Status", task.StatusName(ts.Status()))
+	field("Priority", task.PriorityName(ts.Priority()))
+	if ts.HasDue() {
+		due := ts.FormatDue()
+		if ts.IsOverdue(time.Now()) {
+			due += " (overdue)"
+		}
+		field("Due", due)
+	}
+	field("Repeats", ts.Recurrence().String())
+	if ts.Project() != "" {
+		field("Project", ts.Project())
+	}
+	if len(ts.Tags()) != 0 {
+		field("Tags", strings.Join(ts.Tags(), ", "))
+	}
+	if ts.Parent() != "" {
+		relations("Parent", []string{ts.Parent()})
+	}
+	var uuids []string
+	for _, child := range h.Children(ts.Uuid()) {
+		uuids = append(uuids, child.Uuid())
+	}
+	relations("Subtasks", uuids)
+	relations("Depends on", ts.Dependencies())
+	uuids = nil
+	for _, dependent := range graph.Dependents(ts.Uuid()) {
+		uuids = append(uuids, dependent.Uuid())
+	}
+	relations("Blocks", uuids)
+	for _, timestamp := range []struct {
+		name string
+		time time.Time
+	}{
+		{"Created", ts.Created()},
+		{"Modified", ts.Modified()},
+		{"Started", ts.Started()},
+		{"Completed", ts.Completed()},
+	} {
+		if !timestamp.time.IsZero() {
+			field(timestamp.name, timestamp.time.Format("2006-01-02 15:04"))
+		}
+	}
+	if ts.Description() != "" {
+		width := terminalColumns(os.Stdout)
+		if width == 0 {
+			width = defaultWrapWidth
+		}
+		fmt.Println("Description:")
+		for _, line := range task.WrapText(ts.Description(), width-2) {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	entries, err := history.Entries(ts.Uuid(), time.Time{})
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		fmt.Println("History:")
+	}
+	for _, entry := range entries {
+		fmt.Printf("  %s %s\n", entry.Time.Format("2006-01-02 15:04"),
+			describeChange(entry))
+	}
+	return nil
+}
+
+// Returns true if the given file is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Handle for status marking, the given status must be either "todo", "doing" or
+// "done", the string slice can be empty and contains the uuids of part of it
+// of the tasks to mark. Returns a non-nil error if the given tasks were not
+// marked. A periodic task marked as done is moved to its next occurrence
+// instead. A task whose dependencies are not done can not be marked as doing,
+// unless force is true. A task whose subtasks are not all done is marked as
+// done with its open subtasks if cascade is true, and after confirmation
+// otherwise.
+func handleStatusMark(status string, args []string, force,
+	cascade bool) error {
+	stat, err := task.ParseStatus(status)
+	if err != nil {
+		return err
+	}
+	if stat == task.Done {
+		if args, err = checkOpenSubtasks(args, cascade); err != nil {
+			return err
+		}
+	}
+	var graph *task.DependencyGraph
+	if stat == task.Doing && !force {
+		tasks, err := store.LoadAll()
+		if err != nil {
+			return err
+		}
+		graph = task.NewDependencyGraph(tasks)
+	}
+	return markTasks(args, func(ts *task.Task) error {
+		if graph != nil {
+			if blockers := graph.Blockers(ts); len(blockers) != 0 {
+				var titles []string
+				for _, blocker := range blockers {
+					titles = append(titles, "\""+blocker.Title()+"\"")
+				}
+				return fmt.Errorf("%s: %w: %s, use --force to start it anyway",
+					ts.Uuid(), task.ErrBlockedByDependency,
+					strings.Join(titles, ", "))
+			}
+		}
+		if err := ts.SetStatus(stat); err != nil {
+			return err
+		}
+		if stat == task.Done && ts.Reschedule(time.Now()) {
+			fmt.Printf("> %s\n", ts.Display())
+		}
+		return nil
+	})
+}
+
+// Returns the given uuids or part of it, followed by the uuids of the open
+// subtasks of the tasks they denote if cascade is true. If cascade is false,
+// asks for confirmation before marking as done a task having open subtasks
+// and returns an error if it is not given.
+func checkOpenSubtasks(args []string, cascade bool) ([]string, error) {
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	h := task.NewHierarchy(tasks)
+	res := args
+	for _, uuid := range args {
+		ts, err := task.LoadUnique(store, uuid)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", uuid, err)
+		}
+		open := h.OpenDescendants(ts.Uuid())
+		if len(open) == 0 {
+			continue
+		}
+		if cascade {
+			for _, sub := range open {
+				res = append(res, sub.Uuid())
+			}
+			continue
+		}
+		question := fmt.Sprintf("\"%s\" has %d open subtasks, mark it as "+
+			"done anyway (--cascade marks them as done too)?", ts.Title(),
+			len(open))
+		if !confirm(question) {
+			return nil, errors.New("aborted")
+		}
+	}
+	return res, nil
+}
+
+// Handle for priority marking, the given priority must be either "low",
+// "medium" or "high", the string slice can be empty and contains the uuids or
+// part of it of the tasks to mark. Returns a non-nil error if the given tasks
+// were not marked.
+func handlePriorityMark(priority string, args []string) error {
+	prio, err := task.ParsePriority(priority)
+	if err != nil {
+		return err
+	}
+	return markTasks(args, func(ts *task.Task) error {
+		return ts.SetPriority(prio)
+	})
+}
+
+// Handle for due date marking, the given due date must be parsable by
+// task.ParseDue or be "none" to remove the due date, the string slice can be
+// empty and contains the uuids or part of it of the tasks to mark. Returns a
+// non-nil error if the given tasks were not marked.
+func handleDueMark(due string, args []string) error {
+	var date time.Time
+	if due != "none" {
+		var err error
+		date, err = task.ParseDue(due, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	return markTasks(args, func(ts *task.Task) error {
+		ts.SetDue(date)
+		return nil
+	})
+}
+
+// Handle for recurrence marking, the given rule must be parsable by
+// task.ParseRecurrence, "none" making the tasks not periodic, the string slice
+// can be empty and contains the uuids or part of it of the tasks to mark.
+// Returns a non-nil error if the given tasks were not marked.
+func handleRepeatMark(rule string, args []string) error {
+	rec, err := task.ParseRecurrence(rule)
+	if err != nil {
+		return err
+	}
+	return markTasks(args, func(ts *task.Task) error {
+		ts.SetRecurrence(rec)
+		return nil
+	})
+}
+
+// Handle for tag marking, the given mark must be "+tag" to add the tag or
+// "-tag" to remove it, the string slice can be empty and contains the uuids or
+// part of it of the tasks to mark. Returns a non-nil error if the given tasks
+// were not marked.
+func handleTagMark(mark string, args []string) error {
+	tag := mark[1:]
+	if !task.IsValidTag(tag) {
+		return task.ErrInvalidTag
+	}
+	return markTasks(args, func(ts *task.Task) error {
+		if mark[0] == '-' {
+			ts.RemoveTag(tag)
+			return nil
+		}
+		return ts.AddTag(tag)
+	})
+}
+
+// Handle for dependency marking, the given prefix is the uuid or part of it of
+// the task the tasks depend on, added if add is true and removed otherwise, the
+// string slice can be empty and contains the uuids or part of it of the tasks
+// to mark. Returns a non-nil error if the given tasks were not marked, which
+// is the case if a dependency would create a cycle.
+func handleDependencyMark(prefix string, add bool, args []string) error {
+	if !add {
+		return markTasks(args, func(ts *task.Task) error {
+			var matches []string
+			for _, dep := range ts.Dependencies() {
+				if strings.HasPrefix(dep, prefix) {
+					matches = append(matches, dep)
+				}
+			}
+			if len(matches) != 1 {
+				return fmt.Errorf("%s: %s: no unique dependency", ts.Uuid(),
+					prefix)
+			}
+			ts.RemoveDependency(matches[0])
+			return nil
+		})
+	}
+	dep, err := task.LoadUnique(store, prefix)
+	if err != nil {
+		return fmt.Errorf("%s: %w", prefix, err)
+	}
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+	graph := task.NewDependencyGraph(tasks)
+	return markTasks(args, func(ts *task.Task) error {
+		if err := graph.CheckDependency(ts, dep.Uuid()); err != nil {
+			return fmt.Errorf("%s: %w", ts.Uuid(), err)
+		}
+		if err := ts.AddDependency(dep.Uuid()); err != nil {
+			return err
+		}
+		graph.Update(ts)
+		return nil
+	})
+}
+
+// Handle for parent marking, the given prefix is the uuid or part of it of the
+// new parent of the tasks, or "none" to make them top level tasks, the string
+// slice can be empty and contains the uuids or part of it of the tasks to
+// mark. Returns a non-nil error if the given tasks were not marked, which is
+// the case if a task would become a descendant of itself.
+func handleParentMark(prefix string, args []string) error {
+	if prefix == "none" {
+		return markTasks(args, func(ts *task.Task) error {
+			return ts.SetParent("")
+		})
+	}
+	parent, err := task.LoadUnique(store, prefix)
+	if err != nil {
+		return fmt.Errorf("%s: %w", prefix, err)
+	}
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+	return markTasks(args, func(ts *task.Task) error {
+		// the hierarchy is rebuilt as the previous tasks changed it
+		h := task.NewHierarchy(tasks)
+		if err := h.CheckParent(ts, parent.Uuid()); err != nil {
+			return fmt.Errorf("%s: %w", ts.Uuid(), err)
+		}
+		if err := ts.SetParent(parent.Uuid()); err != nil {
+			return err
+		}
+		for i := range tasks {
+			if tasks[i].Uuid() == ts.Uuid() {
+				tasks[i] = ts
+			}
+		}
+		return nil
+	})
+}
+
+// Handle for project marking, the given project must be a valid project path
+// or "none" to remove the tasks from their project, the string slice can be
+// empty and contains the uuids or part of it of the tasks to mark. Returns a
+// non-nil error if the given tasks were not marked.
+func handleProjectMark(project string, args []string) error {
+	if project == "none" {
+		project = ""
+	} else if !task.IsValidProject(project) {
+		return task.ErrInvalidProject
+	}
+	return markTasks(args, func(ts *task.Task) error {
+		return ts.SetProject(project)
+	})
+}
+
+// Prints the tree of the projects of the tasks matching the given filters,
+// with the number of tasks of every status and the percentage of done tasks of
+// every project, subprojects included.
+func handleProjects(filters []string) error {
+	tasks, err := task.LoadQuery(store, strings.Join(filters, " "))
+	if err != nil {
+		return err
+	}
+	tree := task.ProjectTree(tasks)
+	width := projectsWidth(tree, 0)
+	var printNodes func(nodes []*task.ProjectNode, depth int)
+	printNodes = func(nodes []*task.ProjectNode, depth int) {
+		for _, node := range nodes {
+			name := strings.Repeat("  ", depth) + node.Name
+			fmt.Printf("%-*s  todo %d, doing %d, done %d (%d%%)\n", width,
+				name, node.Todo, node.Doing, node.Done, node.Completion())
+			printNodes(node.Children, depth+1)
+		}
+	}
+	printNodes(tree, 0)
+	return nil
+}
+
+// Returns the width of the longest indented project name of the given nodes
+// at the given depth and of their subprojects.
+func projectsWidth(nodes []*task.ProjectNode, depth int) int {
+	width := 0
+	for _, node := range nodes {
+		width = max(width, 2*depth+len(node.Name),
+			projectsWidth(node.Children, depth+1))
+	}
+	return width
+}
+
+// Prints the tags of the tasks matching the given filters, with their number
+// of tasks.
+func handleTags(filters []string) error {
+	tasks, err := task.LoadQuery(store, strings.Join(filters, " "))
+	if err != nil {
+		return err
+	}
+	for _, count := range task.CountTags(tasks) {
+		fmt.Printf("%s (%d)\n", count.Tag, count.Count)
+	}
+	return nil
+}
+
+// Removes the tasks denoted by the given uuids or part of it. Every uuid must
+// denote exactly one task, otherwise no task is removed. If something wrong
+// happens, returns an error. The args slice can be empty. The tasks depending
+// on removed tasks no longer depend on them, which is reported. The subtasks
+// of removed tasks are removed too if cascade is true, and become subtasks of
+// the parent of the removed task if reparent is true. If none is true, asks
+// whether to remove them. The removed tasks are moved to the trash.
+func handleRemove(args []string, cascade, reparent bool) error {
+	return task.Batch(store, func(s task.Store) error {
+		all, err := s.LoadAll()
+		if err != nil {
+			return err
+		}
+		h := task.NewHierarchy(all)
+		var removed []*task.Task
+		seen := make(map[string]bool)
+		add := func(ts *task.Task) {
+			if !seen[ts.Uuid()] {
+				seen[ts.Uuid()] = true
+				removed = append(removed, ts)
+			}
+		}
+		for _, uuid := range args {
+			ts, err := task.LoadUnique(s, uuid)
+			if err != nil {
+				return fmt.Errorf("%s: %w", uuid, err)
+			}
+			add(ts)
+		}
+		for _, ts := range removed {
+			descendants := h.Descendants(ts.Uuid())
+			if len(descendants) == 0 || reparent {
+				continue
+			}
+			if !cascade {
+				question := fmt.Sprintf("\"%s\" has %d subtasks, remove them "+
+					"too (otherwise they are moved to its parent)?",
+					ts.Title(), len(descendants))
+				if !confirm(question) {
+					continue
+				}
+			}
+			for _, sub := range descendants {
+				add(sub)
+			}
+		}
+		for _, ts := range removed {
+			children, err := task.Reparent(s, ts.Uuid())
+			if err != nil {
+				return err
+			}
+			for _, child := range children {
+				if !seen[child.Uuid()] {
+					fmt.Printf("\"%s\" moved to the parent of removed task "+
+						"%s\n", child.Title(), ts.Uuid())
+				}
+			}
+			// the task is trashed before being removed, so that a failed
+			// removal leaves it in both the list and the trash rather than
+			// in none
+			if err := task.DefaultTrash.Put(ts); err != nil {
+				return err
+			}
+			if err := s.Remove(ts.Uuid()); err != nil {
+				return err
+			}
+		}
+		for _, ts := range removed {
+			dependents, err := task.RemoveDependencies(s, ts.Uuid())
+			if err != nil {
+				return err
+			}
+			for _, dependent := range dependents {
+				fmt.Printf("\"%s\" no longer depends on removed task %s\n",
+					dependent.Title(), ts.Uuid())
+			}
+		}
+		return nil
+	})
+}
+
+// Rewrites every task file in the current format version and reports the files
+// that could not be converted. Returns an error if some files could not be
+// converted.
+func handleMigrate() error {
+	migrator, ok := backendStore.(task.Migrator)
+	if !ok {
+		return errors.New("the " + storeBackend + " store can not be migrated")
+	}
+	migrated, failures, err := migrator.Migrate()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("migrated %d tasks to format version %d\n", migrated,
+		task.FormatVersion)
+	for _, failure := range failures {
+		fmt.Printf("could not convert %s\n", failure.Error())
+	}
+	if len(failures) != 0 {
+		return fmt.Errorf("%d tasks could not be converted", len(failures))
+	}
+	return nil
+}
+
+// Copies every task of the store of backend from to the store of backend to
+// and checks that none was lost. The tasks of from are kept.
+func handleStoreConvert(from, to string) error {
+	if from == to {
+		return errors.New("can not convert a store to itself")
+	}
+	src, err := openStore(listPath, from)
+	if err != nil {
+		return err
+	}
+	defer closeStore(src)
+	dest, err := openStore(listPath, to)
+	if err != nil {
+		return err
+	}
+	defer closeStore(dest)
+	n, err := task.CopyTasks(src, dest)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("converted %d tasks from the %s store to the %s store\n", n,
+		from, to)
+	fmt.Printf("set store = \"%s\" in the configuration file or AGEN_STORE=%s "+
+		"to use the %s store\n", to, to, to)
+	return nil
+}
+
+// Writes a snapshot of the journal store and empties its log.
+func handleStoreCompact() error {
+	journal, ok := backendStore.(*task.JournalStore)
+	if !ok {
+		return errors.New("only the journal store can be compacted")
+	}
+	_, logSize, err := journal.Sizes()
+	if err != nil {
+		return err
+	}
+	if err = journal.Compact(); err != nil {
+		return err
+	}
+	snapshotSize, _, err := journal.Sizes()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("compacted a log of %d bytes, the snapshot is %d bytes\n",
+		logSize, snapshotSize)
+	return nil
+}
+
+// Prints every list with its number of tasks, the list in use being marked
+// with a star.
+func handleLists() error {
+	others, err := task.ListNames(filepath.Join(dataPath, "lists"))
+	if err != nil {
+		return err
+	}
+	names := []string{defaultList}
+	for _, name := range others {
+		if name != defaultList {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		s, err := openStore(listRoot(name), storeBackend)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		tasks, err := s.LoadAll()
+		closeStore(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		mark := " "
+		if name == listName {
+			mark = "*"
+		}
+		fmt.Printf("%s %s (%d tasks)\n", mark, name, len(tasks))
+	}
+	return nil
+}
+
+// Moves the tasks denoted by the given uuids or part of it from the list in use
+// to the list of given name, created if it does not exist. Every uuid must
+// denote exactly one task, and no task of the other list must have the uuid
+// of a moved task, otherwise no task is moved. The moved tasks no longer have
+// the parent and the dependencies that are not in the other list.
+func handleMove(name string, args []string) error {
+	if err := task.CheckListName(name); err != nil {
+		return err
+	}
+	if name == listName {
+		return errors.New("the tasks are already in list " + name)
+	}
+	backend, err := openStore(listRoot(name), storeBackend)
+	if err != nil {
+		return err
+	}
+	defer closeStore(backend)
+	dest := task.NewHistoryStore(backend,
+		task.NewHistory(historyPath(listRoot(name))))
+	_, err = task.MoveTasks(store, dest, args)
+	return err
+}
+
+// Reports the problems of the task files and repairs them if repair is true.
+// Returns an error if problems were found and not repaired.
+func handleDoctor(repair bool) error {
+	files, ok := backendStore.(*task.FileStore)
+	if !ok {
+		return errors.New("doctor only checks the file store")
+	}
+	problems, err := files.Diagnose()
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Println(problem.String())
+	}
+	if len(problems) == 0 {
+		fmt.Println("no problem found")
+		return nil
+	}
+	if !repair {
+		return fmt.Errorf("%d problems found, run agen doctor -repair to "+
+			"repair them", len(problems))
+	}
+	if err = files.Repair(problems); err != nil {
+		return err
+	}
+	fmt.Printf("repaired %d problems\n", len(problems))
+	return nil
+}
+
+// checks every element of args for equality with "-h", "--help" or "help" and
+// if one is found that equals one of the strings, prints usage to the log
+// and returns true
+func checkForHelpAndPrintUsage(args []string, usage string) bool {
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" || arg == "help" || arg == "-help" {
+			fmt.Println(usage)
+			return true
+		}
+	}
+	return false
+}
+
+func agenUsage() string {
+	return `Usage of agen:
+  agen [--dir path] subcommand [--in list] [arguments]
+where --dir sets the data directory holding the tasks, see below, and --in the
+list of the tasks, the default list if not given.
+
+Subcommands:
+  agen newTask: create a new task
+  agen list: list tasks
+  agen mark: mark a task as done, as of high priority, due on a date or tagged
+  agen remove: move tasks to the trash
+  agen trash: list, restore or empty the removed tasks
+  agen archive: move the done tasks to the archive of the list
+  agen unarchive: move archived tasks back to the list
+  agen migrate: rewrite the task files in the current format version
+  agen doctor: check and repair the task files
+  agen store: show or convert the store of the tasks
+  agen tags: list the tags with their number of tasks
+  agen projects: print the project tree with its progress
+  agen search: search the titles and descriptions of the tasks
+  agen show: print every detail of a task
+  agen edit: change the title, the description or other fields of a task
+  agen log: print the history of the changes of a task or of every task
+  agen undo: undo the latest change of the tasks
+  agen redo: redo the latest undone change of the tasks
+  agen lists: list the task lists
+  agen move: move tasks to another list
+
+The data directory is the one given by --dir, otherwise by the AGEN_DIR
+environment variable, otherwise by the "dir" key of the configuration file,
+otherwise $HOME/.agen if it exists, otherwise $XDG_DATA_HOME/agen or
+$HOME/.local/share/agen. It is created if it does not exist.
+
+The configuration file is $XDG_CONFIG_HOME/agen/config.toml, or
+$HOME/.config/agen/config.toml. It holds "key = value" lines, the keys being:
+  dir:      the data directory
+  priority: the default priority of new tasks, low, medium or high
+  status:   the default status of new tasks, todo, doing or done
+  format:   the display format of listed tasks, text or short (shortened uuids)
+  store:    the store backend, file, sqlite or journal
+  list:     the list used when --in is not given
+  archive_after: the number of days after their completion done tasks are
+            archived when tasks are listed, such as 30d
+`
+}
+
+func undoUsage() string {
+	return `Usage of undo and redo:
+  agen undo
+  agen redo
+undo restores the tasks changed by the latest command that changed tasks, and
+not undone yet, to their states before the command: removed tasks come back,
+created tasks are removed and changed tasks get their previous fields back.
+redo makes the latest undone change again. The latest 100 changes of every list
+can be undone, and making a new change forgets the undone changes.
+
+Moving tasks to another list is undone in the current list only, the moved
+tasks being kept in the other list.`
+}
+
+func archiveUsage() string {
+	return `Usage of archive and unarchive:
+  agen archive [--before date]
+  agen unarchive t0 [t1 ...]
+archive moves the done tasks to the archive of the list, only those completed
+before the given date with --before, such as 2026-10-01 or "2026-10-01 14:00".
+Archived tasks are no longer listed nor looked up by uuid, but are listed with
+agen list --archived, which takes the same query. The tasks done before
+completion times were recorded are archived whatever the date.
+
+unarchive moves back to the list the archived tasks of given uuids (or part of
+it).
+
+Setting the "archive_after" key of the configuration file, such as
+archive_after = "30d", archives the tasks done for longer when tasks are
+listed. Archiving and unarchiving are not undone by agen undo.`
+}
+
+func trashUsage() string {
+	return `Usage of trash:
+  agen trash [list]
+  agen trash restore t0 [t1 ...]
+  agen trash empty [--older-than time]
+Removed tasks are moved to the trash of their list with the time they were
+removed, and are no longer listed nor looked up by uuid.
+
+"list" prints the trashed tasks, the latest removed first, with the time they
+were removed. It is the default.
+
+"restore" moves back to the list the trashed tasks of given uuids (or part of
+it). The parent and the dependencies of a restored task that are no longer in
+the list are dropped.
+
+"empty" removes the trashed tasks for good, only those removed before the
+given time with --older-than, either a duration such as 30d (days), 2w (weeks)
+or 12h, or a date such as 2026-10-01.
+
+Example:
+  - to remove for good the tasks trashed more than a month ago:
+      agen trash empty --older-than 30d`
+}
+
+func logUsage() string {
+	return `Usage of log:
+  agen log [--since time] [uuid]
+Prints the changes of the task of given uuid, or beginning of it, or of every
+task if no uuid is given, the oldest first. Every change is printed with its
+time, the task, the old and new values of the changed field and the user who
+made it as user@host. The removed tasks keep their history.
+  --since time  prints only the changes made since the given time, either a
+                duration such as 2d (days), 1w (weeks) or 12h, or a date such
+                as 2026-10-01 or "2026-10-01 14:00"
+`
+}
+
+func showUsage() string {
+	return `Usage of show:
+  agen show uuid
+Prints every field of the task of given uuid, or beginning of it, the tasks it
+is related to, its description, wrapped to the width of the terminal, and the
+history of its changes.
+`
+}
+
+func searchUsage() string {
+	return `Usage of search:
+  agen search [--regex] [--where query] terms...
+Prints the tasks whose title or description holds every term, ignoring case,
+the tasks with the most matches in their title first, then those with the
+most matches in their description. The description lines holding matches are
+printed under their task, and the matches are highlighted on a terminal.
+  --regex       the terms are regular expressions instead of plain text
+  --where query searches only the tasks matching the query, as for list, e.g.
+                --where "todo high"
+
+A term with spaces is searched as a whole, e.g. agen search "release notes".
+`
+}
+
+func listUsage() string {
+	return `Usage of list:
+  agen list [--sort keys] [--group-by field] [--tree] [--format format]
+            [--template template] [--archived] [query]
+where query selects the listed tasks, and:
+  --sort keys      sorts the tasks by the given comma separated keys, the
+                   first being the most significant. A key is one of
+                   priority, status, created, modified, started, completed,
+                   due or title, optionally followed by :asc (the default) or
+                   :desc. Tasks missing the sorted time come last. The
+                   default order is priority:desc,created.
+  --group-by field prints the tasks in groups of same status, priority,
+                   project or tag, each with its number of tasks. A task with
+                   several tags is printed in the group of each tag.
+  --tree           prints the subtasks indented under their parent, with the
+                   number of done subtasks of every parent.
+  --format format  prints the tasks as text, the default, as text with
+                   shortened uuids (short), or with every field as a JSON
+                   array (json), a JSON object per line (ndjson), or CSV or
+                   TSV with a header line (csv, tsv). See the README for the
+                   JSON fields.
+  --template text  prints every task with the given Go text/template, e.g.
+                   '{{.Uuid}} {{.Title}}'. The template gets the task, whose
+                   methods include Title, Description, Status, Priority, Due,
+                   Created, Modified, Started, Completed, Tags, Project,
+                   Parent and Dependencies, and can
+                   call status and priority on Status and Priority to get
+                   their names, join to join a list with a separator and date
+                   to format a time.
+  --archived       lists the archived tasks instead, see agen archive help.
+
+A query is made of filters, one of the following:
+  status: todo, doing, done
+  priority: low, medium, high
+  due: overdue, today, week
+  tags: +tag (tasks having the tag), -tag (tasks not having the tag)
+  dependencies: blocked, ready
+  project: project:path (tasks of the project or of its subprojects)
+  times: created:R, modified:R, started:R, completed:R (tasks whose time is in
+         the range R: today, yesterday, this-week, last-week, this-month,
+         last-month or Nd, the last N days)
+
+"overdue" lists the tasks that are not done and whose due date has passed,
+"today" the tasks due today and "week" the tasks due in the next seven days.
+"blocked" lists the tasks depending on tasks that are not done, and "ready"
+the tasks that are not done and whose dependencies are all done. A task is
+started when it is first marked as doing, and completed when it is marked as
+done.
+
+When several filters from the same category ("status", "priority", "due",
+"dependencies" or "times") are given next to each other, they form a union
+filter, meaning that tasks that satisfy one of the given filters could be
+listed (if not filtered out by the other categories). If filters from different
+categories are given, they form an intersection filter, meaning that a task
+must have a status in the status filters, a priority in the priority filters,
+a due date matching one of the due filters, a dependency state matching one of
+the dependency filters and a time matching one of the time filters. A task
+must also have one of the +tag tags, if any, none of the -tag tags and be in
+one of the project filters, if any.
+
+Filters can also be comparisons of a field to a value:
+  status, priority, due: =, !=, <, <=, >, >=
+  created, modified,     =, !=, <, <=, >, >=
+  started, completed:
+  title, desc:           =, !=, ~ (contains), !~, all case-insensitive
+  project:               = (in the project or a subproject), !=, ~, !~
+  tag:                   = (has the tag), !=, ~ (a tag contains), !~
+where times are written as the due dates of agen mark due:D, a date without
+time comparing to the day of the times, and "none" compares to the tasks without
+the time. Values holding spaces are written between double quotes.
+
+Filters and comparisons are combined with "and", "or", "not" and parentheses,
+"not" binding tighter than "and", itself binding tighter than "or". Filters
+written next to each other without operator are combined as described above,
+and must match together with the comparisons written next to them. Errors are
+reported with their column in the query, the arguments being joined by spaces.
+With the sqlite store, only the queries made of filters written next to each
+other use the indexes of the database, the other queries being matched against
+every task.
+
+Examples:
+  - to list all done tasks: agen list done
+  - to list all done or todo tasks: agen list done todo
+  - to list all todo tasks that have priority high: agen list todo high
+  - to list all overdue tasks of priority high: agen list overdue high
+  - to list all backend tasks not tagged blocked: agen list +backend -blocked
+  - to list all tasks of infra and its subprojects: agen list project:infra
+  - to list all tasks that can be started: agen list todo ready
+  - to list the important deployments due before november:
+      agen list 'priority>=medium and title~"deploy" and due<2026-11-01'
+  - to list the tasks being done or of priority high:
+      agen list '(doing or high)'
+  - to list the tasks to do by due date, then title:
+      agen list --sort due,title todo
+  - to list the tasks by project: agen list --group-by project
+  - to list the tasks done last week, the latest first:
+      agen list --sort completed:desc completed:last-week`
+}
+
+func markUsage() string {
 	return `Usage of mark:
-  agen mark arg [t0 t1 ...]
+  agen mark [--force] [--cascade] arg [t0 t1 ...]
 where arg is one of the following:
   low:    sets the priority of the given tasks to Low
   medium: sets the priority of the given tasks to Medium
@@ -294,12 +2210,157 @@ where arg is one of the following:
   todo:   sets the status of the given tasks to Todo
   doing:  sets the status of the given tasks to Doing
   done:   sets the status of the given tasks to Done
+
+  due:D   sets the due date of the given tasks to D, where D is one of
+          "2006-01-02", "2006-01-02 15:04", "today", "tomorrow", "+3d", "+2w"
+          or "none" to remove the due date
+  repeat:R sets the recurrence rule of the given tasks to R, where R is one of
+          "daily", "weekly", "weekly:mon,thu", "monthly:15", "every:3" (days)
+          or "none" to make the tasks not periodic
+  +tag    adds the tag to the given tasks
+  -tag    removes the tag from the given tasks
+  dep:D   makes the given tasks depend on the task D, a uuid or part of it
+  nodep:D makes the given tasks no longer depend on the task D
+  parent:P makes the given tasks subtasks of the task P, a uuid or part of it,
+          or top level tasks if P is "none"
+  project:P sets the project of the given tasks to P, a dot separated path such
+          as "infra.ci.runners", or "none" to remove the tasks from their
+          project
 and t0 t1 ... denotes the optionnal tasks uuids (or part of it) to mark with
-the given value`
+the given value.
+
+A periodic task marked as done goes back to Todo, due on its next occurrence.
+
+A task depending on tasks that are not done can not be marked as doing, unless
+--force is given. A dependency that would make a task depend on itself,
+directly or not, is rejected.
+
+Marking as done a task whose subtasks are not all done asks for confirmation,
+unless --cascade is given, in which case its subtasks are marked as done too.`
 }
 
 func removeUsage() string {
 	return `Usage of remove:
-  agen remove [t0 t1 ...]
-where [t0 t1 ...] denotes the optionnal tasks uuids (or part of it) to remove.`
+  agen remove [--cascade | --reparent] [t0 t1 ...]
+where [t0 t1 ...] denotes the optionnal tasks uuids (or part of it) to remove.
+
+The subtasks of a removed task are removed too with --cascade, and become
+subtasks of the parent of the removed task with --reparent. Without any of
+them, agen asks whether to remove them.
+
+The tasks that depended on removed tasks no longer depend on them, and are
+reported. The removed tasks are moved to the trash, see agen trash help.`
+}
+
+func tagsUsage() string {
+	return `Usage of tags:
+  agen tags [filter ...]
+prints every tag of the tasks with its number of tasks, sorted by tag. The
+filters are those of agen list and restrict the counted tasks.
+
+Example:
+  - to count the tags of the tasks to do: agen tags todo`
+}
+
+func projectsUsage() string {
+	return `Usage of projects:
+  agen projects [filter ...]
+prints the tree of the projects of the tasks, with the number of tasks to do,
+being done and done and the percentage of done tasks of every project, the
+tasks of its subprojects included. The filters are those of agen list and
+restrict the counted tasks.
+
+Example:
+  - to print the progress of the infra project: agen projects project:infra`
+}
+
+func listsUsage() string {
+	return `Usage of lists:
+  agen lists
+prints every task list with its number of tasks, the list in use being marked
+with a star.
+
+Every subcommand works on a single list, given with --in, or by the "list" key
+of the configuration file, or the default list. The tasks of the default list
+are stored in the data directory and those of the other lists in the lists
+subdirectory. A list is created by adding its first task:
+  agen newTask --in work -title "Write report"`
+}
+
+func moveUsage() string {
+	return `Usage of move:
+  agen move list [t0 t1 ...]
+where list is the name of the list to move the tasks to, created if it does not
+exist, and [t0 t1 ...] denotes the optionnal tasks uuids (or part of it) to
+move from the list in use. No task is moved if a task of the other list has
+the uuid of a moved task. The moved tasks no longer have the parent and the
+dependencies that are neither moved nor in the other list.
+
+Example:
+  - to move a task of the default list to the home list: agen move home 3a
+  - to move it back: agen move --in home default 3a`
+}
+
+func migrateUsage() string {
+	return `Usage of migrate:
+  agen migrate
+rewrites every task file written in an older format version in the current
+version, and reports the files that could not be converted. Older files are
+also converted one by one when they are loaded.`
+}
+
+func editUsage() string {
+	return `Usage of edit:
+  agen edit uuid [-title T] [-desc D] [-periodic[=false]] [-repeat R]
+            [-prio P] [-due D] [-project P]
+changes the given fields of the task of given uuid, or beginning of it, the
+other fields being left unchanged. The values are those of newTask, "none"
+removing the due date, the recurrence rule or the project. The status is
+changed with agen mark, which checks the dependencies and the subtasks of the
+task and moves periodic tasks to their next occurrence.
+
+Without flags, opens $EDITOR, or vi, on a file holding the fields and the
+description of the task, and saves the task with the fields of the file once
+the editor exits. The task is left unchanged if a field is invalid, the edited
+file being kept to try again.`
+}
+
+func doctorUsage() string {
+	return `Usage of doctor:
+  agen doctor [-repair]
+checks every task file and reports the truncated and corrupt files, the files
+not named after the uuid they store and the files storing the same uuid as
+another file. With -repair, truncated, corrupt and duplicate files are moved
+to the quarantine subdirectory of the tasks directory and misnamed files are
+renamed after the uuid they store.
+
+Note that agen list moves the files it can not parse to quarantine on its own.`
+}
+
+func storeUsage() string {
+	return `Usage of store:
+  agen store
+  agen store convert from to
+  agen store compact
+where from and to are store backends, one of:
+  file:    one file per task in the tasks subdirectory of the data directory,
+           the default
+  sqlite:  a single SQLite database in tasks.db in the data directory
+  journal: a single log in journal in the data directory, where every change
+           is appended, and its snapshot in journal.snapshot
+
+Without arguments, prints the backend in use. The backend is chosen with the
+"store" key of the configuration file, or with the AGEN_STORE environment
+variable that takes precedence.
+
+"convert" copies every task of the from store to the to store and checks that
+every task was copied unchanged. The tasks of the from store are kept.
+
+"compact" writes a snapshot of the journal store holding every task and
+empties its log, so that it is fast to load.
+
+Example:
+  - to move the tasks to a SQLite database:
+      agen store convert file sqlite
+      export AGEN_STORE=sqlite`
 }
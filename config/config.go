@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A Config holds the settings of agen read from its configuration file.
+type Config struct {
+	Dir      string // the data directory, empty to use the default one
+	Priority string // the default priority of new tasks
+	Status   string // the default status of new tasks
+	Format   string // the default display format of listed tasks
+	Store    string // the storage backend
+	List     string // the list used when none is given, empty for the default
+	// the number of days after their completion done tasks are archived, 0 to
+	// never archive them automatically
+	ArchiveAfter int
+}
+
+var ErrNoConfigPath = errors.New("neither $XDG_CONFIG_HOME nor $HOME set")
+
+// Returns the configuration used when there is no configuration file.
+func Default() *Config {
+	return &Config{
+		Priority: "medium",
+		Status:   "todo",
+		Format:   "text",
+		Store:    "file",
+	}
+}
+
+// Returns the path of the configuration file: config.toml in the agen
+// subdirectory of $XDG_CONFIG_HOME, or of $HOME/.config if it is not set.
+func DefaultPath() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "agen", "config.toml"), nil
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, ".config", "agen", "config.toml"), nil
+	}
+	return "", ErrNoConfigPath
+}
+
+// Loads the configuration file of given path. A missing file is not an error,
+// the default configuration is then returned.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parses a configuration written in the subset of TOML made of comments and
+// top level "key = value" lines, where values are strings, quoted or not.
+// Missing keys keep their default value.
+//
+// Example:
+//
+//	# where the tasks are stored
+//	dir = "~/tasks"
+//	priority = "high"
+//	store = "sqlite"
+//	list = "work"
+//	archive_after = "30d"
+func Parse(r io.Reader) (*Config, error) {
+	cfg := Default()
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key = strings.TrimSpace(key)
+		value, err := parseValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if err = cfg.set(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Returns the given line without its comment, if any. A '#' in a quoted
+// string does not start a comment.
+func stripComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// Returns the string denoted by the given value, quoted or not.
+func parseValue(value string) (string, error) {
+	if value == "" {
+		return "", errors.New("missing value")
+	}
+	if value[0] != '"' {
+		return value, nil
+	}
+	res, err := strconv.Unquote(value)
+	if err != nil {
+		return "", errors.New("invalid string " + value)
+	}
+	return res, nil
+}
+
+// Sets the setting of given key to the given value. Returns an error if the
+// key is unknown or the value invalid.
+func (c *Config) set(key, value string) error {
+	switch key {
+	case "dir":
+		c.Dir = expandHome(value)
+	case "priority":
+		if value != "low" && value != "medium" && value != "high" {
+			return errors.New("priority must be low, medium or high")
+		}
+		c.Priority = value
+	case "status":
+		if value != "todo" && value != "doing" && value != "done" {
+			return errors.New("status must be todo, doing or done")
+		}
+		c.Status = value
+	case "format":
+		if value != "text" && value != "short" {
+			return errors.New("format must be text or short")
+		}
+		c.Format = value
+	case "list":
+		c.List = value
+	case "store":
+		if value != "file" && value != "sqlite" && value != "journal" {
+			return errors.New("store must be file, sqlite or journal")
+		}
+		c.Store = value
+	case "archive_after":
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if !strings.HasSuffix(value, "d") || err != nil || days <= 0 {
+			return errors.New("archive_after must be a number of days, such as 30d")
+		}
+		c.ArchiveAfter = days
+	default:
+		return errors.New("unknown key " + key)
+	}
+	return nil
+}
+
+// Replaces a leading "~" of the given path by $HOME.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return os.Getenv("HOME") + path[1:]
+	}
+	return path
+}
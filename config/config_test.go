@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEmptyConfigReturnsDefault(t *testing.T) {
+	cfg, err := Parse(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if *cfg != *Default() {
+		t.Fatalf("got %v, want %v", *cfg, *Default())
+	}
+}
+
+func TestParseSetsEveryKey(t *testing.T) {
+	t.Setenv("HOME", "/home/agen")
+	content := `# agen configuration
+dir = "~/tasks" # the data directory
+priority = high
+status = "doing"
+
+format = "short"
+store = 'journal'
+list = work
+archive_after = "30d"
+`
+	_, err := Parse(strings.NewReader(content))
+	if err == nil {
+		t.Fatalf("expected error for single quoted string")
+	}
+	content = strings.Replace(content, "'journal'", "\"journal\"", 1)
+	cfg, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := Config{Dir: "/home/agen/tasks", Priority: "high",
+		Status: "doing", Format: "short", Store: "journal", List: "work",
+		ArchiveAfter: 30}
+	if *cfg != want {
+		t.Fatalf("got %v, want %v", *cfg, want)
+	}
+}
+
+func TestParseQuotedHashIsNotAComment(t *testing.T) {
+	cfg, err := Parse(strings.NewReader(`dir = "/tmp/#agen" # comment`))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if cfg.Dir != "/tmp/#agen" {
+		t.Fatalf("got \"%s\", want \"%s\"", cfg.Dir, "/tmp/#agen")
+	}
+}
+
+func TestParseErrorsGiveTheLine(t *testing.T) {
+	contents := map[string]string{
+		"priority = \"medium\"\nunknown = 1": "line 2: unknown key unknown",
+		"\nstore = \"postgres\"":             "line 2: store must be file, sqlite or journal",
+		"status":                             "line 1: expected key = value",
+		"format =":                           "line 1: missing value",
+		"archive_after = 30":                 "line 1: archive_after must be a number of days, such as 30d",
+		"# comment\n\ndir = \"/tmp":          "line 3: invalid string \"/tmp",
+	}
+	for content, want := range contents {
+		_, err := Parse(strings.NewReader(content))
+		if err == nil {
+			t.Fatalf("%q: expected error", content)
+		}
+		if err.Error() != want {
+			t.Fatalf("got \"%s\", want \"%s\"", err.Error(), want)
+		}
+	}
+}
+
+func TestLoadMissingFileReturnsDefault(t *testing.T) {
+	cfg, err := Load(filepath.Join(os.TempDir(), "agen-missing-config.toml"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if *cfg != *Default() {
+		t.Fatalf("got %v, want %v", *cfg, *Default())
+	}
+}
+
+func TestDefaultPathPrefersXdgConfigHome(t *testing.T) {
+	t.Setenv("HOME", "/home/agen")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	path, err := DefaultPath()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if path != "/home/agen/.config/agen/config.toml" {
+		t.Fatalf("got %s", path)
+	}
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	path, err = DefaultPath()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if path != "/xdg/agen/config.toml" {
+		t.Fatalf("got %s", path)
+	}
+}
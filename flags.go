@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// The flags taken from anywhere in the arguments are only recognized with two
+// dashes, as "-name" is the filter excluding the tasks tagged name and the mark
+// removing the tag name.
+
+// Returns the arguments without the flag of given name, given as --name value
+// or --name=value anywhere in the arguments, and its value, empty if not
+// given. Returns an error if the flag has no value.
+func takeValueFlag(args []string, name string) ([]string, string, error) {
+	var rest []string
+	value := ""
+	for i := 0; i < len(args); i++ {
+		flag, flagValue, hasValue := strings.Cut(args[i], "=")
+		if flag != "--"+name {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, "", errors.New("missing value of flag: " + args[i])
+			}
+			i++
+			flagValue = args[i]
+		}
+		value = flagValue
+	}
+	return rest, value, nil
+}
+
+// Returns the arguments without the boolean flag of given name, given as
+// --name anywhere in the arguments, and true if it was given.
+func takeFlag(args []string, name string) ([]string, bool) {
+	var rest []string
+	given := false
+	for _, arg := range args {
+		if arg == "--"+name {
+			given = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, given
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTakeValueFlagLeavesTagFilters(t *testing.T) {
+	args := []string{"-in", "--in", "work", "todo", "--sort=due"}
+	rest, list, err := takeValueFlag(args, "in")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if list != "work" {
+		t.Fatalf("got %v, want %v", list, "work")
+	}
+	want := []string{"-in", "todo", "--sort=due"}
+	if !slices.Equal(rest, want) {
+		t.Fatalf("got %v, want %v", rest, want)
+	}
+	rest, list, err = takeValueFlag([]string{"-in"}, "in")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if list != "" || !slices.Equal(rest, []string{"-in"}) {
+		t.Fatalf("got %v and %v, want -in left as a filter", list, rest)
+	}
+	if _, _, err = takeValueFlag([]string{"--in"}, "in"); err == nil {
+		t.Fatalf("expected error for --in without value")
+	}
+}
+
+func TestTakeFlagLeavesTagFilters(t *testing.T) {
+	rest, tree := takeFlag([]string{"-tree", "+work"}, "tree")
+	if tree {
+		t.Fatalf("-tree taken as --tree")
+	}
+	if !slices.Equal(rest, []string{"-tree", "+work"}) {
+		t.Fatalf("got %v, want %v", rest, []string{"-tree", "+work"})
+	}
+	rest, tree = takeFlag([]string{"+work", "--tree"}, "tree")
+	if !tree || !slices.Equal(rest, []string{"+work"}) {
+		t.Fatalf("got %v and %v, want true and %v", tree, rest,
+			[]string{"+work"})
+	}
+}
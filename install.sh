@@ -1,3 +1,3 @@
 #!/bin/sh
 
-mkdir -p $HOME/.agen/tasks/
+go build
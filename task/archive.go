@@ -0,0 +1,78 @@
+package task
+
+import (
+	"time"
+)
+
+// Returns true if the task is done and was completed before the given time,
+// or is done at all if the time is zero. The tasks done before completion
+// times were recorded are considered completed before any time.
+func (t *Task) IsArchivable(before time.Time) bool {
+	if t.status != Done {
+		return false
+	}
+	return before.IsZero() || t.completed.Before(before)
+}
+
+// Moves the done tasks of the given store completed before the given time,
+// every done task if it is zero, to the given archive store, and returns them.
+// The archived tasks keep their parent and dependencies, so that they are
+// related again once unarchived.
+func Archive(store, archive Store, before time.Time) ([]*Task, error) {
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	var archived []*Task
+	for _, t := range tasks {
+		if t.IsArchivable(before) {
+			archived = append(archived, t)
+		}
+	}
+	if len(archived) == 0 {
+		return nil, nil
+	}
+	// the tasks are saved in the archive before being removed, so that an
+	// interruption leaves them in both stores rather than in none
+	err = Batch(archive, func(s Store) error {
+		for _, t := range archived {
+			if err := s.Save(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = Batch(store, func(s Store) error {
+		for _, t := range archived {
+			if err := s.Remove(t.uuid); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// Moves the only task of the given archive store whose uuid has the given
+// prefix back to the given store, and returns it. Its parent is dropped if it
+// would make a cycle. Returns ErrTaskNotFound if there is no such task and
+// ErrUuidNotUnique if there are several.
+func Unarchive(archive, store Store, prefix string) (*Task, error) {
+	t, err := LoadUnique(archive, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if err = dropCyclicParent(store, t); err != nil {
+		return nil, err
+	}
+	if err = store.Save(t); err != nil {
+		return nil, err
+	}
+	return t, archive.Remove(t.uuid)
+}
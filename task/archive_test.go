@@ -0,0 +1,89 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestArchiveMovesDoneTasksCompletedBefore(t *testing.T) {
+	store := NewMemoryStore()
+	archive := NewMemoryStore()
+	todo, _ := NewTask("todo", "", false, Medium, Todo)
+	old, _ := NewTask("old", "", false, Medium, Done)
+	old.completed = old.completed.AddDate(0, 0, -40)
+	recent, _ := NewTask("recent", "", false, Medium, Done)
+	for _, ts := range []*Task{todo, old, recent} {
+		store.Save(ts)
+	}
+	archived, err := Archive(store, archive, now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(archived) != 1 || archived[0].Uuid() != old.Uuid() {
+		t.Fatalf("got %v, want the old task", archived)
+	}
+	if exists, _ := archive.Exists(old.Uuid()); !exists {
+		t.Fatalf("archived task not in the archive")
+	}
+	if exists, _ := store.Exists(old.Uuid()); exists {
+		t.Fatalf("archived task still in the store")
+	}
+	if archived, err = Archive(store, archive, time.Time{}); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(archived) != 1 || archived[0].Uuid() != recent.Uuid() {
+		t.Fatalf("got %v, want the recent task", archived)
+	}
+	if tasks, _ := store.LoadAll(); len(tasks) != 1 {
+		t.Fatalf("got %d tasks, want 1", len(tasks))
+	}
+}
+
+func TestDoneTaskWithoutCompletionTimeIsArchivable(t *testing.T) {
+	ts, _ := NewTask("done", "", false, Medium, Done)
+	ts.completed = time.Time{}
+	if !ts.IsArchivable(now().AddDate(-10, 0, 0)) {
+		t.Fatalf("done task without completion time not archivable")
+	}
+}
+
+func TestUnarchiveMovesTaskBack(t *testing.T) {
+	store := NewMemoryStore()
+	archive := NewMemoryStore()
+	ts, _ := NewTask("done", "", false, Medium, Done)
+	archive.Save(ts)
+	unarchived, err := Unarchive(archive, store, ts.Uuid()[:8])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if unarchived.Uuid() != ts.Uuid() {
+		t.Fatalf("got %v, want %v", unarchived.Uuid(), ts.Uuid())
+	}
+	if exists, _ := store.Exists(ts.Uuid()); !exists {
+		t.Fatalf("unarchived task not in the store")
+	}
+	_, err = Unarchive(archive, store, ts.Uuid())
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrTaskNotFound)
+	}
+}
+
+func TestUnarchiveDropsParentMakingCycle(t *testing.T) {
+	store := NewMemoryStore()
+	archive := NewMemoryStore()
+	parent := newSubtask(t, "parent", Todo, nil)
+	archived := newSubtask(t, "archived", Done, parent)
+	child := newSubtask(t, "child", Todo, archived)
+	parent.SetParent(child.Uuid())
+	store.Save(parent)
+	store.Save(child)
+	archive.Save(archived)
+	unarchived, err := Unarchive(archive, store, archived.Uuid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if unarchived.Parent() != "" {
+		t.Fatalf("got parent %v, want none", unarchived.Parent())
+	}
+}
@@ -0,0 +1,73 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The prefix of the temporary files written next to the task files before
+// being renamed over them. Uuids never start with a dot, so temporary files
+// never match a uuid prefix.
+const tempFilePrefix = ".agen-tmp-"
+
+// The age after which a temporary file is considered left over by an
+// interrupted save, younger ones may belong to a save in progress.
+const tempFileMaxAge = time.Minute
+
+// Writes the given data to the file of given path so that the file either has
+// its previous content or the new one, even if the process dies or the disk
+// fills during the write. The data is written to a temporary file in the same
+// directory, flushed to disk and then renamed over the file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, name := filepath.Split(filepath.Clean(path))
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, tempFilePrefix+name+"-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// makes the rename durable, not all systems support syncing directories
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Returns true if the file of given name is a temporary file of
+// writeFileAtomic.
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, tempFilePrefix)
+}
+
+// Removes the given temporary file of the given directory if it was left over
+// by an interrupted save.
+func removeLeftoverTempFile(dir string, entry os.DirEntry) {
+	info, err := entry.Info()
+	if err != nil {
+		return
+	}
+	if time.Since(info.ModTime()) > tempFileMaxAge {
+		os.Remove(filepath.Join(dir, entry.Name()))
+	}
+}
@@ -0,0 +1,111 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomicReplacesContentWithoutLeavingTempFiles(t *testing.T) {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	path := filepath.Join(dirname, "file")
+	if err = os.WriteFile(path, []byte("old content"), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = writeFileAtomic(path, []byte("new"), 0600); err != nil {
+		t.Fatalf(err.Error())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if string(data) != "new" {
+		t.Fatalf("got \"%s\", want \"%s\"", data, "new")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("got mode %v, want %v", info.Mode().Perm(), 0600)
+	}
+	entries, err := os.ReadDir(dirname)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d files, want 1", len(entries))
+	}
+}
+
+func TestWriteFileAtomicInMissingDirReturnsError(t *testing.T) {
+	err := writeFileAtomic("/nonexistent-agen-dir/file", []byte("data"), 0644)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestLoadTasksFromSkipsTempFilesAndRemovesLeftoverOnes(t *testing.T) {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = ts.saveAt(dirname); err != nil {
+		t.Fatalf(err.Error())
+	}
+	truncated := ts.encode()[:10]
+	leftover := filepath.Join(dirname, tempFilePrefix+ts.Uuid()+"-1")
+	inProgress := filepath.Join(dirname, tempFilePrefix+ts.Uuid()+"-2")
+	if err = os.WriteFile(leftover, truncated, 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = os.WriteFile(inProgress, truncated, 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	old := time.Now().Add(-2 * tempFileMaxAge)
+	if err = os.Chtimes(leftover, old, old); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err := loadTasksFrom(dirname)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("got %d tasks, want 1", len(tasks))
+	}
+	if _, err = os.Stat(leftover); !os.IsNotExist(err) {
+		t.Fatalf("expected leftover temp file to be removed")
+	}
+	if _, err = os.Stat(inProgress); err != nil {
+		t.Fatalf("expected temp file in progress to be kept")
+	}
+}
+
+func TestCountFilesWithPrefixIgnoresTempFiles(t *testing.T) {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	path := filepath.Join(dirname, tempFilePrefix+"abc")
+	if err = os.WriteFile(path, []byte{}, 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	count, err := countFilesWithPrefixAt(dirname, tempFilePrefix)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 0 {
+		t.Fatalf("got %d, want 0", count)
+	}
+}
@@ -0,0 +1,199 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+const DependenciesMaxCount = 255
+
+var (
+	ErrSelfDependency      = errors.New("a task can not depend on itself")
+	ErrDependencyCycle     = errors.New("dependency would create a cycle")
+	ErrTooManyDependencies = errors.New("too many dependencies (max 255)")
+	ErrInvalidDependency   = errors.New("invalid dependency uuid")
+	ErrBlockedByDependency = errors.New("task blocked by unfinished dependencies")
+)
+
+// Returns the sorted uuids of the tasks this task depends on.
+func (t *Task) Dependencies() []string {
+	return slices.Clone(t.deps)
+}
+
+// Makes this task depend on the task of given uuid, if it does not yet.
+// Returns an error if the uuid is the one of this task or is invalid, or if
+// the task already has DependenciesMaxCount dependencies. Cycles are checked
+// by DependencyGraph.CheckDependency.
+func (t *Task) AddDependency(uuid string) error {
+	if uuid == "" || len(uuid) > 255 {
+		return ErrInvalidDependency
+	}
+	if uuid == t.uuid {
+		return ErrSelfDependency
+	}
+	i, found := slices.BinarySearch(t.deps, uuid)
+	if found {
+		return nil
+	}
+	if len(t.deps) == DependenciesMaxCount {
+		return ErrTooManyDependencies
+	}
+	t.deps = slices.Insert(t.deps, i, uuid)
+	return nil
+}
+
+// Removes the dependency of this task on the task of given uuid. Returns false
+// if the task does not depend on it.
+func (t *Task) RemoveDependency(uuid string) bool {
+	i, found := slices.BinarySearch(t.deps, uuid)
+	if found {
+		t.deps = slices.Delete(t.deps, i, i+1)
+	}
+	return found
+}
+
+// A DependencyGraph gives the dependencies between a set of tasks. A
+// dependency on a task that is not part of the graph, for example because it
+// was removed, is ignored.
+type DependencyGraph struct {
+	tasks map[string]*Task // the tasks by uuid
+}
+
+// Returns the dependency graph of the given tasks.
+func NewDependencyGraph(tasks []*Task) *DependencyGraph {
+	g := &DependencyGraph{tasks: make(map[string]*Task, len(tasks))}
+	for _, t := range tasks {
+		g.tasks[t.uuid] = t
+	}
+	return g
+}
+
+// Adds the given task to the graph, replacing the task of same uuid.
+func (g *DependencyGraph) Update(t *Task) {
+	g.tasks[t.uuid] = t
+}
+
+// Returns the task of given uuid, nil if it is not part of the graph.
+func (g *DependencyGraph) Task(uuid string) *Task {
+	return g.tasks[uuid]
+}
+
+// Returns the dependencies of the given task that are not done, sorted by
+// uuid.
+func (g *DependencyGraph) Blockers(t *Task) []*Task {
+	var res []*Task
+	for _, uuid := range t.deps {
+		if dep, ok := g.tasks[uuid]; ok && dep.status != Done {
+			res = append(res, dep)
+		}
+	}
+	return res
+}
+
+// Returns true if the given task has dependencies that are not done.
+func (g *DependencyGraph) IsBlocked(t *Task) bool {
+	return len(g.Blockers(t)) != 0
+}
+
+// Returns true if the given task is not done and all its dependencies are.
+func (g *DependencyGraph) IsReady(t *Task) bool {
+	return t.status != Done && !g.IsBlocked(t)
+}
+
+// Returns the tasks depending on the task of given uuid, sorted by uuid.
+func (g *DependencyGraph) Dependents(uuid string) []*Task {
+	var res []*Task
+	for _, t := range g.tasks {
+		if _, found := slices.BinarySearch(t.deps, uuid); found {
+			res = append(res, t)
+		}
+	}
+	slices.SortFunc(res, func(a, b *Task) int {
+		return strings.Compare(a.uuid, b.uuid)
+	})
+	return res
+}
+
+// Returns nil if the given task can depend on the task of given uuid, that is
+// if it is another task of the graph that does not already depend, directly or
+// not, on the given task.
+func (g *DependencyGraph) CheckDependency(t *Task, uuid string) error {
+	if uuid == t.uuid {
+		return ErrSelfDependency
+	}
+	if _, ok := g.tasks[uuid]; !ok {
+		return ErrTaskNotFound
+	}
+	visited := make(map[string]bool)
+	pending := []string{uuid}
+	for len(pending) != 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if current == t.uuid {
+			return ErrDependencyCycle
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		if dep, ok := g.tasks[current]; ok {
+			pending = append(pending, dep.deps...)
+		}
+	}
+	return nil
+}
+
+// Returns true if filter equals one of "blocked" or "ready"
+func IsValidDependencyFilter(filter string) bool {
+	return filter == "blocked" || filter == "ready"
+}
+
+// Parses the strings and returns the slice of dependency filters found,
+// without duplicates
+func ParseDependencyFilterFrom(strs []string) []string {
+	var res []string
+	for _, str := range strs {
+		if IsValidDependencyFilter(str) && !slices.Contains(res, str) {
+			res = append(res, str)
+		}
+	}
+	return res
+}
+
+// Returns true if the task satisfies at least one of the given dependency
+// filters in the given graph
+func matchesDependencyFilters(g *DependencyGraph, t *Task,
+	filters []string) bool {
+	for _, filter := range filters {
+		switch filter {
+		case "blocked":
+			if g.IsBlocked(t) {
+				return true
+			}
+		case "ready":
+			if g.IsReady(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Removes the dependencies on the task of given uuid from the tasks of the
+// given store depending on it, and saves them. Returns the changed tasks.
+func RemoveDependencies(store Store, uuid string) ([]*Task, error) {
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	dependents := NewDependencyGraph(tasks).Dependents(uuid)
+	for _, t := range dependents {
+		t.RemoveDependency(uuid)
+		t.Touch()
+		if err = store.Save(t); err != nil {
+			return nil, err
+		}
+	}
+	return dependents, nil
+}
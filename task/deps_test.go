@@ -0,0 +1,115 @@
+package task
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAddDependencyKeepsSortedUuidsWithoutDuplicates(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, uuid := range []string{"b", "a", "b"} {
+		if err = ts.AddDependency(uuid); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	if !slices.Equal(ts.Dependencies(), []string{"a", "b"}) {
+		t.Fatalf("got %v, want [a b]", ts.Dependencies())
+	}
+	if err = ts.AddDependency(ts.Uuid()); err != ErrSelfDependency {
+		t.Fatalf("got %v, want %v", err, ErrSelfDependency)
+	}
+	if !ts.RemoveDependency("a") || ts.RemoveDependency("a") {
+		t.Fatalf("expected a to be removed once")
+	}
+}
+
+func TestCheckDependencyRejectsCycles(t *testing.T) {
+	a, _ := NewDefault("a")
+	b, _ := NewDefault("b")
+	c, _ := NewDefault("c")
+	b.AddDependency(a.Uuid())
+	c.AddDependency(b.Uuid())
+	graph := NewDependencyGraph([]*Task{a, b, c})
+	if err := graph.CheckDependency(a, c.Uuid()); err != ErrDependencyCycle {
+		t.Fatalf("got %v, want %v", err, ErrDependencyCycle)
+	}
+	if err := graph.CheckDependency(a, a.Uuid()); err != ErrSelfDependency {
+		t.Fatalf("got %v, want %v", err, ErrSelfDependency)
+	}
+	if err := graph.CheckDependency(c, a.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := graph.CheckDependency(a, "missing"); err != ErrTaskNotFound {
+		t.Fatalf("got %v, want %v", err, ErrTaskNotFound)
+	}
+}
+
+func TestFilterTasksByDependencyState(t *testing.T) {
+	done, _ := NewTask("done", "", false, Medium, Done)
+	todo, _ := NewDefault("todo")
+	blocked, _ := NewDefault("blocked")
+	blocked.AddDependency(todo.Uuid())
+	unblocked, _ := NewDefault("unblocked")
+	unblocked.AddDependency(done.Uuid())
+	orphan, _ := NewDefault("orphan")
+	orphan.AddDependency("removed")
+	tasks := []*Task{done, todo, blocked, unblocked, orphan}
+	tests := []struct {
+		filters []string
+		want    []*Task
+	}{
+		{[]string{"blocked"}, []*Task{blocked}},
+		{[]string{"ready"}, []*Task{todo, unblocked, orphan}},
+		{[]string{"ready", "blocked"}, []*Task{todo, blocked, unblocked,
+			orphan}},
+	}
+	for _, test := range tests {
+		got, err := FilterTasks(tasks, test.filters)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !slices.Equal(got, test.want) {
+			t.Fatalf("%v: got %d tasks, want %d", test.filters, len(got),
+				len(test.want))
+		}
+	}
+}
+
+func TestRemoveCleansDependents(t *testing.T) {
+	a, _ := NewDefault("a")
+	b, _ := NewDefault("b")
+	b.AddDependency(a.Uuid())
+	store := useMemoryStore(t, a, b)
+	if err := Remove(a.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err := store.Load(b.Uuid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks[0].Dependencies()) != 0 {
+		t.Fatalf("got dependencies %v, want none", tasks[0].Dependencies())
+	}
+}
+
+func TestSQLiteStoreLoadFilteredBlockedLooksUpEveryTask(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	dep, _ := NewTask("dep", "", false, Low, Doing)
+	blocked, _ := NewTask("blocked", "", false, High, Todo)
+	blocked.AddDependency(dep.Uuid())
+	for _, ts := range []*Task{dep, blocked} {
+		if err := store.Save(ts); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	tasks, err := LoadFiltered(store, []string{"todo", "blocked"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 1 || tasks[0].Uuid() != blocked.Uuid() {
+		t.Fatalf("got %d tasks, want the blocked one", len(tasks))
+	}
+}
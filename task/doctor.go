@@ -0,0 +1,190 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// The name of the subdirectory of the tasks directory where the task files
+// that can not be loaded are moved.
+const QuarantineDir = "quarantine"
+
+const (
+	ProblemTruncated = iota
+	ProblemCorrupt
+	ProblemMisnamed
+	ProblemDuplicate
+)
+
+// A Problem is an issue found in a task file of the tasks directory.
+type Problem struct {
+	File string // the name of the task file
+	Kind int    // ProblemTruncated, ProblemCorrupt, ProblemMisnamed or ProblemDuplicate
+	Uuid string // the uuid stored in the file, empty if it could not be read
+	Err  error  // the reason a truncated or corrupt file could not be read
+}
+
+// Returns a description of the problem.
+func (p *Problem) String() string {
+	switch p.Kind {
+	case ProblemTruncated:
+		return fmt.Sprintf("truncated: %s (%s)", p.File, p.Err)
+	case ProblemCorrupt:
+		return fmt.Sprintf("corrupt: %s (%s)", p.File, p.Err)
+	case ProblemMisnamed:
+		return fmt.Sprintf("misnamed: %s stores task %s", p.File, p.Uuid)
+	default:
+		return fmt.Sprintf("duplicate: %s stores task %s, also stored in "+
+			"another file", p.File, p.Uuid)
+	}
+}
+
+// Returns true if the given error, returned while loading a task file, means
+// that the content of the file is damaged. Files of unsupported format
+// versions are not considered damaged, they may have been written by a more
+// recent version of agen.
+func isCorruptionError(err error) bool {
+	corruptions := []error{ErrInvalidTaskFileSize, ErrTitleTooShort,
+		ErrTitleTooLong, ErrDescTooLong, ErrInvalidPriority, ErrInvalidStatus,
+		ErrInvalidRecurrence, ErrInvalidTag, ErrTooManyTags,
+		ErrInvalidProject, ErrInvalidDependency, ErrSelfDependency,
+		ErrTooManyDependencies, ErrSelfParent, ErrInvalidParent,
+		ErrInvalidTaskUuid}
+	for _, corruption := range corruptions {
+		if errors.Is(err, corruption) {
+			return true
+		}
+	}
+	return false
+}
+
+// Moves the file of given name of the given tasks directory to its quarantine
+// subdirectory and logs the reason. A file already in quarantine under the
+// same name is kept, the new one gets a suffix.
+func quarantine(path, name string, reason error) error {
+	dir := filepath.Join(path, QuarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, name)
+	if _, err := os.Lstat(dest); err == nil {
+		dest += "." + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	if err := os.Rename(filepath.Join(path, name), dest); err != nil {
+		return err
+	}
+	logf("moved %s to %s: %s", name, dest, reason)
+	return nil
+}
+
+// Checks every task file of the given tasks directory and returns the problems
+// found: truncated or corrupt files, files not named after the uuid they store
+// and files storing the same uuid as another one.
+func diagnoseAt(path string) ([]*Problem, error) {
+	if path == "" {
+		return nil, ErrInvalidLoadPath
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var problems []*Problem
+	filesByUuid := make(map[string][]string)
+	var uuids []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || isTempFile(entry.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		ts, _, err := decodeTask(data)
+		if err != nil {
+			if errors.Is(err, ErrInvalidTaskFileSize) {
+				problems = append(problems, &Problem{File: entry.Name(),
+					Kind: ProblemTruncated, Err: err})
+			} else if isCorruptionError(err) {
+				problems = append(problems, &Problem{File: entry.Name(),
+					Kind: ProblemCorrupt, Err: err})
+			}
+			continue
+		}
+		if _, ok := filesByUuid[ts.uuid]; !ok {
+			uuids = append(uuids, ts.uuid)
+		}
+		filesByUuid[ts.uuid] = append(filesByUuid[ts.uuid], entry.Name())
+	}
+	for _, uuid := range uuids {
+		files := filesByUuid[uuid]
+		// the file named after the uuid is the one kept, otherwise the first
+		// one is and has to be renamed
+		kept := files[0]
+		for _, file := range files {
+			if file == uuid {
+				kept = file
+			}
+		}
+		if kept != uuid {
+			problems = append(problems, &Problem{File: kept,
+				Kind: ProblemMisnamed, Uuid: uuid})
+		}
+		for _, file := range files {
+			if file != kept {
+				problems = append(problems, &Problem{File: file,
+					Kind: ProblemDuplicate, Uuid: uuid})
+			}
+		}
+	}
+	return problems, nil
+}
+
+// Repairs the given problems of the given tasks directory: truncated, corrupt
+// and duplicate files are moved to quarantine, misnamed files are renamed
+// after the uuid they store. Every problem is attempted, the returned error
+// joins the errors of the problems that could not be repaired.
+func repairAt(path string, problems []*Problem) error {
+	var errs []error
+	// renames come last, once the files they could collide with are gone
+	for _, p := range problems {
+		if p.Kind == ProblemMisnamed {
+			continue
+		}
+		reason := p.Err
+		if p.Kind == ProblemDuplicate {
+			reason = errors.New("duplicate of task " + p.Uuid)
+		}
+		if err := quarantine(path, p.File, reason); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, p := range problems {
+		if p.Kind != ProblemMisnamed {
+			continue
+		}
+		dest := filepath.Join(path, p.Uuid)
+		if _, err := os.Lstat(dest); err == nil {
+			errs = append(errs, fmt.Errorf("could not rename %s: %s exists",
+				p.File, p.Uuid))
+			continue
+		}
+		if err := os.Rename(filepath.Join(path, p.File), dest); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Checks every task file of the store and returns the problems found.
+func (s *FileStore) Diagnose() ([]*Problem, error) {
+	return diagnoseAt(s.path)
+}
+
+// Repairs the given problems, found by Diagnose.
+func (s *FileStore) Repair(problems []*Problem) error {
+	return repairAt(s.path, problems)
+}
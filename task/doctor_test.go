@@ -0,0 +1,140 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Creates a temporary tasks directory holding a valid task, a truncated file,
+// a corrupt file, a misnamed file and a duplicate of the valid task. Returns
+// the directory and the uuids of the valid and of the misnamed tasks.
+func newDamagedTasksDir(t *testing.T) (string, string, string) {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	valid, _ := NewDefault("valid")
+	if err = valid.saveAt(dirname); err != nil {
+		t.Fatalf(err.Error())
+	}
+	misnamed, _ := NewDefault("misnamed")
+	files := map[string][]byte{
+		"truncated":   valid.encode()[:12],
+		"corrupt":     {0, 'A', 'G', 'N', 1, 1, 'x', 0, 0, 0, 7, Todo, 0},
+		"misnamed":    misnamed.encode(),
+		"duplicate-1": valid.encode(),
+	}
+	for name, data := range files {
+		err = os.WriteFile(filepath.Join(dirname, name), data, 0644)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	return dirname, valid.Uuid(), misnamed.Uuid()
+}
+
+func TestLoadTasksFromQuarantinesUnparsableFilesAndGoesOn(t *testing.T) {
+	dirname, _, _ := newDamagedTasksDir(t)
+	defer os.RemoveAll(dirname)
+	tasks, err := loadTasksFrom(dirname)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("got %d tasks, want 3", len(tasks))
+	}
+	for _, name := range []string{"truncated", "corrupt"} {
+		_, err = os.Stat(filepath.Join(dirname, QuarantineDir, name))
+		if err != nil {
+			t.Fatalf("expected %s in quarantine: %s", name, err.Error())
+		}
+	}
+	tasks, err = loadTasksFrom(dirname)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("got %d tasks on second load, want 3", len(tasks))
+	}
+}
+
+func TestDiagnoseReportsEveryKindOfProblem(t *testing.T) {
+	dirname, valid, misnamed := newDamagedTasksDir(t)
+	defer os.RemoveAll(dirname)
+	problems, err := diagnoseAt(dirname)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	slices.Sort(got)
+	want := []string{
+		"corrupt: corrupt (priority must be Low, Medium or High)",
+		"duplicate: duplicate-1 stores task " + valid +
+			", also stored in another file",
+		"misnamed: misnamed stores task " + misnamed,
+		"truncated: truncated (invalid task file size)",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestRepairLeavesNoProblem(t *testing.T) {
+	dirname, valid, misnamed := newDamagedTasksDir(t)
+	defer os.RemoveAll(dirname)
+	problems, err := diagnoseAt(dirname)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = repairAt(dirname, problems); err != nil {
+		t.Fatalf(err.Error())
+	}
+	problems, err = diagnoseAt(dirname)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(problems) != 0 {
+		t.Fatalf("got %d problems, want 0", len(problems))
+	}
+	for _, uuid := range []string{valid, misnamed} {
+		if _, err = os.Stat(filepath.Join(dirname, uuid)); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+}
+
+func TestRepairQuarantinesTaskOfUuidNamingAnotherFile(t *testing.T) {
+	dirname := t.TempDir()
+	tasksDir := filepath.Join(dirname, "tasks")
+	if err := os.Mkdir(tasksDir, 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+	evil, _ := NewDefault("evil")
+	evil.uuid = "../../x"
+	err := os.WriteFile(filepath.Join(tasksDir, "evil"), evil.encode(), 0644)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	problems, err := diagnoseAt(tasksDir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(problems) != 1 || problems[0].Kind != ProblemCorrupt {
+		t.Fatalf("got %v, want a corrupt file", problems)
+	}
+	if err = repairAt(tasksDir, problems); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err = os.Lstat(filepath.Join(tasksDir, "..", "..", "x")); err == nil {
+		t.Fatalf("repair wrote outside the tasks directory")
+	}
+	_, err = os.Stat(filepath.Join(tasksDir, QuarantineDir, "evil"))
+	if err != nil {
+		t.Fatalf("expected evil in quarantine: %s", err.Error())
+	}
+}
@@ -0,0 +1,136 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The line of an edit text after which the description starts.
+const editDescriptionLine = "description:"
+
+var (
+	ErrInvalidEditText   = errors.New("invalid edit text")
+	ErrStatusNotEditable = errors.New("the status can not be edited, mark the task instead")
+)
+
+// Returns the fields of the task as a text to edit, made of a "field: value"
+// line per field followed by the description, and applied by Edit. Lines
+// starting with '#' before the description are comments.
+func (t *Task) EditText() string {
+	var sb strings.Builder
+	sb.WriteString("# Edit the task and save the file, lines starting with # are ignored.\n")
+	sb.WriteString("# Empty fields are unset, tags are separated by spaces, and the\n")
+	sb.WriteString("# description is the text following its line. The status is\n")
+	sb.WriteString("# changed by marking the task.\n")
+	due := ""
+	if t.HasDue() {
+		due = t.FormatDue()
+	}
+	fields := [][2]string{
+		{"title", t.title},
+		{"status", StatusName(t.Status())},
+		{"priority", PriorityName(t.Priority())},
+		{"due", due},
+		{"repeat", t.recurrence.String()},
+		{"project", t.project},
+		{"tags", strings.Join(t.tags, " ")},
+	}
+	for _, field := range fields {
+		fmt.Fprintf(&sb, "%s: %s\n", field[0], field[1])
+	}
+	sb.WriteString(editDescriptionLine + "\n")
+	sb.WriteString(t.desc)
+	return sb.String()
+}
+
+// Sets the fields of the task to those of the given text, in the form
+// returned by EditText, due dates being read relatively to now. The task is
+// left unchanged if any field is invalid. A missing field is left unchanged,
+// except the description which is then emptied. The status can not be changed,
+// as marking a task checks its dependencies and subtasks and reschedules it if
+// it is periodic: a status other than the one of the task is an error wrapping
+// ErrStatusNotEditable.
+func (t *Task) Edit(text string, now time.Time) error {
+	edited := *t
+	edited.tags = slices.Clone(t.tags)
+	edited.desc = ""
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed == editDescriptionLine {
+			desc := strings.Join(lines[i+1:], "")
+			if err := edited.SetDescription(strings.TrimRight(desc, "\n")); err != nil {
+				return err
+			}
+			break
+		}
+		name, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return fmt.Errorf("%w: line %d: not a field", ErrInvalidEditText, i+1)
+		}
+		if err := edited.setField(name, strings.TrimSpace(value), now); err != nil {
+			return fmt.Errorf("%w: line %d: %w", ErrInvalidEditText, i+1, err)
+		}
+	}
+	*t = edited
+	return nil
+}
+
+// Sets the field of given name, as written by EditText, to the given value.
+func (t *Task) setField(name, value string, now time.Time) error {
+	switch name {
+	case "title":
+		return t.SetTitle(value)
+	case "status":
+		status, err := ParseStatus(value)
+		if err != nil {
+			return err
+		}
+		if status != t.status {
+			return ErrStatusNotEditable
+		}
+	case "priority":
+		prio, err := ParsePriority(value)
+		if err != nil {
+			return err
+		}
+		return t.SetPriority(prio)
+	case "due":
+		if value == "" || value == "none" {
+			t.SetDue(time.Time{})
+			return nil
+		}
+		due, err := ParseDue(value, now)
+		if err != nil {
+			return err
+		}
+		t.SetDue(due)
+	case "repeat":
+		if value == "" {
+			value = "none"
+		}
+		rule, err := ParseRecurrence(value)
+		if err != nil {
+			return err
+		}
+		t.SetRecurrence(rule)
+	case "project":
+		return t.SetProject(value)
+	case "tags":
+		t.tags = nil
+		for _, tag := range strings.Fields(value) {
+			if err := t.AddTag(tag); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
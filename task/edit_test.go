@@ -0,0 +1,89 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEditTextRoundTrips(t *testing.T) {
+	ts := newExportTask(t)
+	edited := *ts
+	now := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if err := edited.Edit(ts.EditText(), now); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if edited.Title() != ts.Title() || edited.Description() != ts.Description() ||
+		edited.Status() != ts.Status() || edited.Priority() != ts.Priority() ||
+		!edited.Due().Equal(ts.Due()) || edited.Recurrence() != ts.Recurrence() ||
+		edited.Project() != ts.Project() ||
+		!slices.Equal(edited.Tags(), ts.Tags()) {
+		t.Fatalf("got %v, want %v", edited.Record(), ts.Record())
+	}
+}
+
+func TestEditChangesFields(t *testing.T) {
+	ts := newExportTask(t)
+	text := strings.Join([]string{
+		"# comment",
+		"title: Renamed",
+		"status: doing",
+		"due:",
+		"repeat: none",
+		"tags: b a",
+		"description:",
+		"first line",
+		"# not a comment",
+	}, "\n")
+	if err := ts.Edit(text, time.Now()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if ts.Title() != "Renamed" || ts.Status() != Doing || ts.HasDue() ||
+		ts.IsPeriodic() || !slices.Equal(ts.Tags(), []string{"a", "b"}) {
+		t.Fatalf("got %v", ts.Record())
+	}
+	if ts.Priority() != High || ts.Project() != "web.site" {
+		t.Fatalf("got %v, want missing fields unchanged", ts.Record())
+	}
+	if want := "first line\n# not a comment"; ts.Description() != want {
+		t.Fatalf("got %q, want %q", ts.Description(), want)
+	}
+}
+
+func TestEditWithInvalidFieldLeavesTaskUnchanged(t *testing.T) {
+	for _, text := range []string{
+		"title: Renamed\npriority: urgent",
+		"title:",
+		"title: Renamed\nsize: large",
+		"title: Renamed\nno field here",
+		"title: Renamed\nstatus: done",
+	} {
+		ts := newExportTask(t)
+		want := ts.Record()
+		err := ts.Edit(text, time.Now())
+		if err == nil {
+			t.Fatalf("%q: expected error", text)
+		}
+		if !errors.Is(err, ErrInvalidEditText) {
+			t.Fatalf("%q: got %v, want %v", text, err, ErrInvalidEditText)
+		}
+		if ts.Title() != want.Title || ts.Priority() != High {
+			t.Fatalf("%q: task changed to %v", text, ts.Record())
+		}
+	}
+}
+
+func TestEditRefusesToChangeStatus(t *testing.T) {
+	ts := newExportTask(t)
+	due := ts.Due()
+	err := ts.Edit("status: done", time.Now())
+	if !errors.Is(err, ErrStatusNotEditable) {
+		t.Fatalf("got %v, want %v", err, ErrStatusNotEditable)
+	}
+	if ts.Status() != Doing || !ts.Completed().IsZero() ||
+		!ts.Due().Equal(due) {
+		t.Fatalf("periodic task changed to %v", ts.Record())
+	}
+}
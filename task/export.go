@@ -0,0 +1,162 @@
+package task
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var ErrInvalidExportFormat = errors.New("export format must be json, ndjson, csv or tsv")
+
+// The columns of the CSV and TSV exports, in the order of the fields of
+// TaskRecord.
+var exportColumns = []string{"uuid", "title", "description", "status",
+	"priority", "periodic", "recurrence", "due", "tags", "project",
+	"dependencies", "parent", "created", "modified", "started", "completed"}
+
+// A TaskRecord holds every field of a task, as exported in JSON. Its JSON form
+// is stable: fields may be added, but are never renamed nor removed, and
+// always present, the missing times being null and the missing project and
+// parent empty strings.
+type TaskRecord struct {
+	Uuid         string     `json:"uuid"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Status       string     `json:"status"`     // "todo", "doing" or "done"
+	Priority     string     `json:"priority"`   // "low", "medium" or "high"
+	Periodic     bool       `json:"periodic"`   // true if the task recurs
+	Recurrence   string     `json:"recurrence"` // the rule, "none" if not periodic
+	Due          *time.Time `json:"due"`        // in RFC 3339 format
+	Tags         []string   `json:"tags"`
+	Project      string     `json:"project"`
+	Dependencies []string   `json:"dependencies"` // the uuids of the dependencies
+	Parent       string     `json:"parent"`       // the uuid of the parent
+	Created      *time.Time `json:"created"`      // in RFC 3339 format
+	Modified     *time.Time `json:"modified"`     // in RFC 3339 format
+	Started      *time.Time `json:"started"`      // the first time doing
+	Completed    *time.Time `json:"completed"`    // the time done
+}
+
+// Returns the record of the task.
+func (t *Task) Record() TaskRecord {
+	r := TaskRecord{
+		Uuid:         t.uuid,
+		Title:        t.title,
+		Description:  t.desc,
+		Status:       StatusName(t.Status()),
+		Priority:     PriorityName(t.Priority()),
+		Periodic:     t.IsPeriodic(),
+		Recurrence:   t.recurrence.String(),
+		Tags:         append([]string{}, t.tags...),
+		Project:      t.project,
+		Dependencies: append([]string{}, t.deps...),
+		Parent:       t.parent,
+	}
+	// returns a pointer to the given time, nil if it is zero
+	optional := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	r.Due = optional(t.due)
+	r.Created = optional(t.created)
+	r.Modified = optional(t.modified)
+	r.Started = optional(t.started)
+	r.Completed = optional(t.completed)
+	return r
+}
+
+// Returns the fields of the record in the order of exportColumns, the lists
+// being comma separated and the missing times empty.
+func (r TaskRecord) columns() []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return []string{r.Uuid, r.Title, r.Description, r.Status, r.Priority,
+		strconv.FormatBool(r.Periodic), r.Recurrence, formatTime(r.Due),
+		strings.Join(r.Tags, ","), r.Project,
+		strings.Join(r.Dependencies, ","), r.Parent, formatTime(r.Created),
+		formatTime(r.Modified), formatTime(r.Started), formatTime(r.Completed)}
+}
+
+// Writes the given tasks to w in the given format: "json" for an array of
+// TaskRecord, "ndjson" for one TaskRecord per line, "csv" or "tsv" for a
+// header line naming the columns followed by a line per task. CSV and TSV
+// fields holding separators, quotes or newlines are quoted.
+func Export(w io.Writer, tasks []*Task, format string) error {
+	switch format {
+	case "json":
+		records := []TaskRecord{}
+		for _, t := range tasks {
+			records = append(records, t.Record())
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, t := range tasks {
+			if err := enc.Encode(t.Record()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		cw.Write(exportColumns)
+		for _, t := range tasks {
+			cw.Write(t.Record().columns())
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return ErrInvalidExportFormat
+	}
+}
+
+// The functions available in the templates of ParseTemplate.
+var templateFuncs = template.FuncMap{
+	"status":   StatusName,
+	"priority": PriorityName,
+	"join":     strings.Join,
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04")
+	},
+}
+
+// Parses the given text/template, executed on a *Task. Besides the methods of
+// Task, the template can call "status" and "priority" on Status and Priority
+// to get their names, "join" to join a list with a separator and "date" to
+// format a time, empty if it is zero.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("task").Funcs(templateFuncs).Parse(text)
+}
+
+// Writes the given tasks to w with the given template, each followed by a
+// newline.
+func ExecuteTemplate(w io.Writer, tasks []*Task, tmpl *template.Template) error {
+	for _, t := range tasks {
+		if err := tmpl.Execute(w, t); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package task
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newExportTask(t *testing.T) *Task {
+	ts, err := NewTask("Write, \"docs\"", "line one\nline\ttwo", false, High,
+		Doing)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetRecurrence(Weekly(time.Monday))
+	ts.SetDue(time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC))
+	ts.AddTag("docs")
+	ts.AddTag("release")
+	ts.SetProject("web.site")
+	return ts
+}
+
+func TestExportJSONHasEveryField(t *testing.T) {
+	ts := newExportTask(t)
+	var buf bytes.Buffer
+	if err := Export(&buf, []*Task{ts}, "json"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(decoded) != 1 {
+		t.Fatalf("got %d tasks, want 1", len(decoded))
+	}
+	record := decoded[0]
+	for _, column := range exportColumns {
+		if _, ok := record[column]; !ok {
+			t.Fatalf("missing field %q in %v", column, record)
+		}
+	}
+	want := map[string]any{"title": "Write, \"docs\"", "status": "doing",
+		"priority": "high", "periodic": true, "recurrence": "weekly:mon",
+		"due": "2026-03-02T00:00:00Z", "project": "web.site", "parent": ""}
+	for field, value := range want {
+		if record[field] != value {
+			t.Fatalf("%s: got %v, want %v", field, record[field], value)
+		}
+	}
+	if deps, ok := record["dependencies"].([]any); !ok || len(deps) != 0 {
+		t.Fatalf("got dependencies %v, want an empty list", record["dependencies"])
+	}
+}
+
+func TestExportEmptyJSONIsAnArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, nil, "json"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Fatalf("got %q, want []", got)
+	}
+}
+
+func TestExportNDJSONWritesALinePerTask(t *testing.T) {
+	tasks := []*Task{newExportTask(t), newExportTask(t)}
+	var buf bytes.Buffer
+	if err := Export(&buf, tasks, "ndjson"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var record TaskRecord
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if record.Uuid != tasks[1].Uuid() {
+		t.Fatalf("got %v, want %v", record.Uuid, tasks[1].Uuid())
+	}
+}
+
+func TestExportCSVAndTSVQuoteFields(t *testing.T) {
+	ts := newExportTask(t)
+	for _, format := range []string{"csv", "tsv"} {
+		var buf bytes.Buffer
+		if err := Export(&buf, []*Task{ts}, format); err != nil {
+			t.Fatalf(err.Error())
+		}
+		r := csv.NewReader(&buf)
+		if format == "tsv" {
+			r.Comma = '\t'
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: %s", format, err.Error())
+		}
+		if len(rows) != 2 || !slices.Equal(rows[0], exportColumns) {
+			t.Fatalf("%s: got %v, want a header and a row", format, rows)
+		}
+		if rows[1][1] != ts.Title() || rows[1][2] != ts.Description() ||
+			rows[1][8] != "docs,release" {
+			t.Fatalf("%s: got %v", format, rows[1])
+		}
+	}
+	if err := Export(&bytes.Buffer{}, nil, "xml"); err != ErrInvalidExportFormat {
+		t.Fatalf("got %v, want %v", err, ErrInvalidExportFormat)
+	}
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	ts := newExportTask(t)
+	tmpl, err := ParseTemplate(
+		`{{priority .Priority}} {{.Title}} [{{join .Tags ","}}] {{date .Due}}`)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	var buf bytes.Buffer
+	if err = ExecuteTemplate(&buf, []*Task{ts}, tmpl); err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := "high Write, \"docs\" [docs,release] 2026-03-02 00:00\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+	if _, err = ParseTemplate("{{.Title"); err == nil {
+		t.Fatalf("expected error for invalid template")
+	}
+}
@@ -0,0 +1,433 @@
+package task
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The current version of the task file format.
+//
+// Version 0 files have no header. They hold the title, the description, the
+// periodicity flag, the priority, the status and the uuid, optionally followed
+// by the due date and then by the recurrence rule.
+//
+// Version 1 files start with the magic value and the version byte, followed by
+// the version 0 fields, the due date and the recurrence rule being mandatory.
+//
+// Version 2 files add the tags after the recurrence rule: their number on one
+// byte, followed by every tag preceded by its length on one byte.
+//
+// Version 3 files add the project path after the tags, preceded by its length
+// on one byte.
+//
+// Version 4 files add the dependencies after the project path: their number on
+// one byte, followed by the uuid of every task the task depends on, preceded
+// by its length on one byte.
+//
+// Version 5 files add the uuid of the parent task after the dependencies,
+// preceded by its length on one byte, 0 for a top level task.
+//
+// Version 6 files add the creation time after the parent, as a presence flag
+// followed by its unix time on eight bytes.
+//
+// Version 7 files add the modification, start and completion times after the
+// creation time, each written as the creation time.
+//
+// Version 8 files add the deletion time of trashed tasks after the completion
+// time, written as the creation time.
+const FormatVersion = 8
+
+// The magic value starting every versioned task file. Its first byte is 0,
+// which can not start a version 0 file as titles are never empty.
+var formatMagic = []byte{0x00, 'A', 'G', 'N'}
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported task file format version")
+	ErrInvalidTaskUuid    = errors.New("invalid task uuid")
+)
+
+// An encoder appends the fields of a task file to a byte slice.
+type encoder struct {
+	data []byte
+}
+
+// Appends a byte.
+func (e *encoder) byte(b byte) {
+	e.data = append(e.data, b)
+}
+
+// Appends a big endian 16 bits integer.
+func (e *encoder) uint16(n uint16) {
+	e.data = binary.BigEndian.AppendUint16(e.data, n)
+}
+
+// Appends a big endian 32 bits integer.
+func (e *encoder) uint32(n uint32) {
+	e.data = binary.BigEndian.AppendUint32(e.data, n)
+}
+
+// Appends a big endian 64 bits integer.
+func (e *encoder) uint64(n uint64) {
+	e.data = binary.BigEndian.AppendUint64(e.data, n)
+}
+
+// Appends a string preceded by its length on one byte.
+func (e *encoder) string8(s string) {
+	e.byte(byte(len(s)))
+	e.data = append(e.data, s...)
+}
+
+// Appends a string preceded by its length on two bytes.
+func (e *encoder) string16(s string) {
+	e.uint16(uint16(len(s)))
+	e.data = append(e.data, s...)
+}
+
+// Appends a presence flag followed by the unix time of the given time, 0 if
+// it is zero.
+func (e *encoder) time(t time.Time) {
+	if t.IsZero() {
+		e.byte(0)
+		e.uint64(0)
+		return
+	}
+	e.byte(1)
+	e.uint64(uint64(t.Unix()))
+}
+
+// A decoder reads the fields of a task file. Once a read goes past the end of
+// the data, every following read returns zero values and err is set to
+// ErrInvalidTaskFileSize.
+type decoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+// Returns the next n bytes, or nil if there are less than n bytes left.
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.data) < d.offset+n {
+		d.err = ErrInvalidTaskFileSize
+		return nil
+	}
+	res := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return res
+}
+
+// Returns true if every byte has been read.
+func (d *decoder) done() bool {
+	return d.offset == len(d.data)
+}
+
+// Reads a byte.
+func (d *decoder) byte() byte {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// Reads a big endian 16 bits integer.
+func (d *decoder) uint16() uint16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+// Reads a big endian 32 bits integer.
+func (d *decoder) uint32() uint32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// Reads a big endian 64 bits integer.
+func (d *decoder) uint64() uint64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// Reads a string preceded by its length on one byte.
+func (d *decoder) string8() string {
+	return string(d.next(int(d.byte())))
+}
+
+// Reads a string preceded by its length on two bytes.
+func (d *decoder) string16() string {
+	return string(d.next(int(d.uint16())))
+}
+
+// Reads a time written by encoder.time.
+func (d *decoder) time() time.Time {
+	present := d.byte()
+	sec := d.uint64()
+	if present == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(sec), 0)
+}
+
+// Returns the content of the task file of this task, in the current format
+// version.
+func (t *Task) encode() []byte {
+	e := encoder{}
+	e.data = append(e.data, formatMagic...)
+	e.byte(FormatVersion)
+	e.string8(t.title)
+	e.string16(t.desc)
+	if t.IsPeriodic() {
+		e.byte(1)
+	} else {
+		e.byte(0)
+	}
+	e.byte(t.priority)
+	e.byte(t.status)
+	e.string8(t.uuid)
+	e.time(t.due)
+	e.byte(t.recurrence.kind)
+	e.byte(t.recurrence.weekdays)
+	e.byte(t.recurrence.monthDay)
+	e.uint16(t.recurrence.interval)
+	e.byte(byte(len(t.tags)))
+	for _, tag := range t.tags {
+		e.string8(tag)
+	}
+	e.string8(t.project)
+	e.byte(byte(len(t.deps)))
+	for _, dep := range t.deps {
+		e.string8(dep)
+	}
+	e.string8(t.parent)
+	e.time(t.created)
+	e.time(t.modified)
+	e.time(t.started)
+	e.time(t.completed)
+	e.time(t.deleted)
+	return e.data
+}
+
+// Returns the format version of the given task file content. Content without
+// header is of version 0.
+func formatVersionOf(data []byte) (int, error) {
+	if !bytes.HasPrefix(data, formatMagic) {
+		return 0, nil
+	}
+	if len(data) < len(formatMagic)+1 {
+		return 0, ErrInvalidTaskFileSize
+	}
+	version := int(data[len(formatMagic)])
+	if version < 1 || version > FormatVersion {
+		return 0, ErrUnsupportedVersion
+	}
+	return version, nil
+}
+
+// Decodes the given task file content, of any format version. Returns the task
+// and the format version the content was written in.
+func decodeTask(data []byte) (*Task, int, error) {
+	version, err := formatVersionOf(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	d := decoder{data: data}
+	if version > 0 {
+		d.offset = len(formatMagic) + 1
+	}
+	title := d.string8()
+	desc := d.string16()
+	isPeriodic := d.byte() == 1
+	priority := d.byte()
+	status := d.byte()
+	id := d.string8()
+	if d.err != nil {
+		return nil, 0, d.err
+	}
+	newTask, err := NewTask(title, desc, isPeriodic, priority, status)
+	if err != nil {
+		return nil, 0, err
+	}
+	// the uuid names the file of the task, it must not be able to name
+	// another file
+	if _, err = uuid.Parse(id); err != nil {
+		return nil, 0, ErrInvalidTaskUuid
+	}
+	newTask.uuid = id
+	newTask.created = time.Time{}
+	newTask.modified = time.Time{}
+	newTask.started = time.Time{}
+	newTask.completed = time.Time{}
+	// version 0 files written before due dates were introduced end after the
+	// uuid, and those written before recurrence rules were introduced only
+	// have the periodicity flag, loaded as a daily recurrence
+	if version == 0 && d.done() {
+		return newTask, version, nil
+	}
+	newTask.due = d.time()
+	if d.err != nil {
+		return nil, 0, d.err
+	}
+	if version == 0 && d.done() {
+		return newTask, version, nil
+	}
+	rec := Recurrence{
+		kind:     d.byte(),
+		weekdays: d.byte(),
+		monthDay: d.byte(),
+		interval: d.uint16(),
+	}
+	if d.err != nil {
+		return nil, 0, d.err
+	}
+	if !rec.isValid() {
+		return nil, 0, ErrInvalidRecurrence
+	}
+	newTask.recurrence = rec
+	if version >= 2 {
+		count := int(d.byte())
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+		for i := 0; i < count; i++ {
+			tag := d.string8()
+			if d.err != nil {
+				return nil, 0, d.err
+			}
+			if err = newTask.AddTag(tag); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if version >= 3 {
+		project := d.string8()
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+		if err = newTask.SetProject(project); err != nil {
+			return nil, 0, err
+		}
+	}
+	if version >= 4 {
+		count := int(d.byte())
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+		for i := 0; i < count; i++ {
+			dep := d.string8()
+			if d.err != nil {
+				return nil, 0, d.err
+			}
+			if err = newTask.AddDependency(dep); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if version >= 5 {
+		parent := d.string8()
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+		if err = newTask.SetParent(parent); err != nil {
+			return nil, 0, err
+		}
+	}
+	if version >= 6 {
+		newTask.created = d.time()
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+	}
+	if version >= 7 {
+		newTask.modified = d.time()
+		newTask.started = d.time()
+		newTask.completed = d.time()
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+	}
+	if version >= 8 {
+		newTask.deleted = d.time()
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+	}
+	if version > 0 && !d.done() {
+		return nil, 0, ErrInvalidTaskFileSize
+	}
+	return newTask, version, nil
+}
+
+// A MigrationError reports a task file that could not be converted to the
+// current format version.
+type MigrationError struct {
+	File string // the name of the task file
+	Err  error  // the reason of the failure
+}
+
+func (e *MigrationError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+// Rewrites in the current format version every task file of the given
+// directory that is written in an older version. Returns the number of
+// rewritten files and the errors of the files that could not be converted. The
+// returned error is non-nil if the directory could not be read.
+func migrateAt(path string) (int, []*MigrationError, error) {
+	if path == "" {
+		return 0, nil, ErrInvalidLoadPath
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	migrated := 0
+	var failures []*MigrationError
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || isTempFile(entry.Name()) {
+			continue
+		}
+		file := filepath.Join(path, entry.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			failures = append(failures, &MigrationError{entry.Name(), err})
+			continue
+		}
+		ts, version, err := decodeTask(data)
+		if err != nil {
+			failures = append(failures, &MigrationError{entry.Name(), err})
+			continue
+		}
+		if version == FormatVersion {
+			continue
+		}
+		if err = writeFileAtomic(file, ts.encode(), 0644); err != nil {
+			failures = append(failures, &MigrationError{entry.Name(), err})
+			continue
+		}
+		migrated++
+	}
+	return migrated, failures, nil
+}
+
+// Rewrites in the current format version every task file of the store that
+// is written in an older version. Returns the number of rewritten files and
+// the errors of the files that could not be converted.
+func (s *FileStore) Migrate() (int, []*MigrationError, error) {
+	return migrateAt(s.path)
+}
@@ -0,0 +1,234 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Returns the version 0 content of the given task, with the given number of
+// optional trailing fields: 0 for none, 1 for the due date and 2 for the due
+// date and the recurrence rule.
+func encodeVersion0(ts *Task, optionalFields int) []byte {
+	e := encoder{}
+	e.string8(ts.title)
+	e.string16(ts.desc)
+	if ts.IsPeriodic() {
+		e.byte(1)
+	} else {
+		e.byte(0)
+	}
+	e.byte(ts.priority)
+	e.byte(ts.status)
+	e.string8(ts.uuid)
+	if optionalFields >= 1 {
+		e.time(ts.due)
+	}
+	if optionalFields >= 2 {
+		e.byte(ts.recurrence.kind)
+		e.byte(ts.recurrence.weekdays)
+		e.byte(ts.recurrence.monthDay)
+		e.uint16(ts.recurrence.interval)
+	}
+	return e.data
+}
+
+// Returns the version 1 content of the given task.
+func encodeVersion1(ts *Task) []byte {
+	data := append([]byte{}, formatMagic...)
+	data = append(data, 1)
+	return append(data, encodeVersion0(ts, 2)...)
+}
+
+func TestEncodeStartsWithMagicAndVersion(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	version, err := formatVersionOf(ts.encode())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if version != FormatVersion {
+		t.Fatalf("got %d, want %d", version, FormatVersion)
+	}
+}
+
+func TestDecodeEncodedTaskReturnsSameTask(t *testing.T) {
+	ts, err := NewTask("test", "a description", false, High, Doing)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetDue(time.Date(2026, time.November, 1, 9, 0, 0, 0, time.Local))
+	rule, _ := Monthly(15)
+	ts.SetRecurrence(rule)
+	ts.AddTag("backend")
+	ts.AddTag("release-1.4")
+	ts.SetProject("infra.ci")
+	ts.AddDependency("2b1f0c3e-0000-4000-8000-000000000000")
+	ts.SetParent("7c0e2d4a-0000-4000-8000-000000000000")
+	decoded, version, err := decodeTask(ts.encode())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if version != FormatVersion {
+		t.Fatalf("got version %d, want %d", version, FormatVersion)
+	}
+	if decoded.Title() != ts.Title() ||
+		decoded.Description() != ts.Description() ||
+		decoded.Priority() != ts.Priority() ||
+		decoded.Status() != ts.Status() || decoded.Uuid() != ts.Uuid() ||
+		!decoded.Due().Equal(ts.Due()) ||
+		decoded.Recurrence() != ts.Recurrence() ||
+		!slices.Equal(decoded.Tags(), ts.Tags()) ||
+		decoded.Project() != ts.Project() ||
+		!slices.Equal(decoded.Dependencies(), ts.Dependencies()) ||
+		decoded.Parent() != ts.Parent() ||
+		!decoded.Created().Equal(ts.Created()) ||
+		!decoded.Modified().Equal(ts.Modified()) ||
+		!decoded.Started().Equal(ts.Started()) ||
+		!decoded.Completed().Equal(ts.Completed()) {
+		t.Fatalf("expected same task")
+	}
+}
+
+func TestDecodeVersion1ContentHasNoTags(t *testing.T) {
+	ts, err := NewDefault("v1")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.AddTag("lost")
+	decoded, version, err := decodeTask(encodeVersion1(ts))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if version != 1 {
+		t.Fatalf("got version %d, want 1", version)
+	}
+	if len(decoded.Tags()) != 0 {
+		t.Fatalf("got tags %v, want none", decoded.Tags())
+	}
+	if !decoded.Created().IsZero() || !decoded.Modified().IsZero() ||
+		!decoded.Started().IsZero() {
+		t.Fatalf("got creation time %v, want none", decoded.Created())
+	}
+}
+
+func TestDecodeVersion0Contents(t *testing.T) {
+	ts, err := NewTask("old", "", true, Low, Todo)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetDue(time.Date(2026, time.November, 1, 0, 0, 0, 0, time.Local))
+	ts.SetRecurrence(Weekly(time.Tuesday))
+	for fields := 0; fields <= 2; fields++ {
+		decoded, version, err := decodeTask(encodeVersion0(ts, fields))
+		if err != nil {
+			t.Fatalf("%d fields: %s", fields, err.Error())
+		}
+		if version != 0 {
+			t.Fatalf("got version %d, want 0", version)
+		}
+		if decoded.Uuid() != ts.Uuid() {
+			t.Fatalf("got %s, want %s", decoded.Uuid(), ts.Uuid())
+		}
+		if decoded.HasDue() != (fields > 0) {
+			t.Fatalf("%d fields: got due %v", fields, decoded.Due())
+		}
+		wantRule := Daily()
+		if fields == 2 {
+			wantRule = ts.Recurrence()
+		}
+		if decoded.Recurrence() != wantRule {
+			t.Fatalf("%d fields: got %s, want %s", fields,
+				decoded.Recurrence(), wantRule)
+		}
+	}
+}
+
+func TestDecodeUnsupportedVersionReturnsError(t *testing.T) {
+	ts, err := NewDefault("future")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	data := ts.encode()
+	data[len(formatMagic)] = FormatVersion + 1
+	if _, _, err = decodeTask(data); err != ErrUnsupportedVersion {
+		t.Fatalf("got %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
+
+func TestDecodeTruncatedContentReturnsError(t *testing.T) {
+	ts, err := NewDefault("truncated")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	data := ts.encode()
+	if _, _, err = decodeTask(data[:len(data)-1]); err != ErrInvalidTaskFileSize {
+		t.Fatalf("got %v, want %v", err, ErrInvalidTaskFileSize)
+	}
+}
+
+func TestLoadTaskFromVersion0FileUpgradesItInPlace(t *testing.T) {
+	ts, err := NewTask("old", "desc", false, Medium, Doing)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	path := filepath.Join(dirname, ts.Uuid())
+	if err = os.WriteFile(path, encodeVersion0(ts, 0), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err = loadTaskFrom(path); err != nil {
+		t.Fatalf(err.Error())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	version, err := formatVersionOf(data)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if version != FormatVersion {
+		t.Fatalf("got version %d, want %d", version, FormatVersion)
+	}
+}
+
+func TestMigrateRewritesOldFilesAndReportsFailures(t *testing.T) {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	old, _ := NewDefault("old")
+	current, _ := NewDefault("current")
+	err = os.WriteFile(filepath.Join(dirname, old.Uuid()),
+		encodeVersion0(old, 1), 0644)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = current.saveAt(dirname); err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = os.WriteFile(filepath.Join(dirname, "broken"), []byte{3, 'a'}, 0644)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	migrated, failures, err := migrateAt(dirname)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if migrated != 1 {
+		t.Fatalf("got %d migrated files, want 1", migrated)
+	}
+	if len(failures) != 1 || failures[0].File != "broken" {
+		t.Fatalf("got failures %v, want broken", failures)
+	}
+}
@@ -0,0 +1,246 @@
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The actions of history entries.
+const (
+	ActionCreate = "create" // a task saved for the first time
+	ActionUpdate = "update" // a field of a task changed
+	ActionRemove = "remove" // a task removed
+)
+
+// The user changing tasks, as "user@host", recorded in the history entries.
+var Actor = defaultActor()
+
+// The columns of TaskRecord whose changes are recorded in the history, the
+// others being either fixed or following from them.
+var historyFields = []string{"title", "description", "status", "priority",
+	"recurrence", "due", "tags", "project", "dependencies", "parent"}
+
+// Returns the name of the current user and of the host as "user@host".
+func defaultActor() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return name + "@" + host
+}
+
+// A HistoryEntry records a change of a task.
+type HistoryEntry struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`  // the user who made the change
+	Action string    `json:"action"` // ActionCreate, ActionUpdate or ActionRemove
+	Uuid   string    `json:"uuid"`
+	Title  string    `json:"title"`           // the title of the task
+	Field  string    `json:"field,omitempty"` // the changed field of an update
+	Old    string    `json:"old,omitempty"`   // the value before an update
+	New    string    `json:"new,omitempty"`   // the value after an update
+}
+
+// Returns the entries recording the change of the task from old to new, old
+// being nil for a created task.
+func historyEntries(old, new *Task) []HistoryEntry {
+	entry := HistoryEntry{Time: now(), Actor: Actor, Uuid: new.uuid,
+		Title: new.title}
+	if old == nil {
+		entry.Action = ActionCreate
+		return []HistoryEntry{entry}
+	}
+	oldColumns, newColumns := old.Record().columns(), new.Record().columns()
+	var entries []HistoryEntry
+	for _, field := range historyFields {
+		i := slices.Index(exportColumns, field)
+		if oldColumns[i] != newColumns[i] {
+			entry.Action = ActionUpdate
+			entry.Field = field
+			entry.Old, entry.New = oldColumns[i], newColumns[i]
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// A History is a log of history entries, kept in a file holding an entry per
+// line in JSON. Entries are only appended.
+type History struct {
+	path string // the path of the log file
+}
+
+// Returns the history of given log file path, created on the first append.
+func NewHistory(path string) *History {
+	return &History{path: path}
+}
+
+// Appends the given entries to the log file in a single write.
+func (h *History) Append(entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Returns the entries of the tasks whose uuid has the given prefix, every
+// task if it is empty, recorded at or after the given time, oldest first. A
+// partially written last line, by an interrupted append, is skipped.
+func (h *History) Entries(prefix string, since time.Time) ([]HistoryEntry,
+	error) {
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var entries []HistoryEntry
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// the last line is either empty or incomplete
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var entry HistoryEntry
+		if err = json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", h.path, n, err)
+		}
+		if strings.HasPrefix(entry.Uuid, prefix) && !entry.Time.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+}
+
+// A HistoryStore is a store recording in a history every change saved to or
+// removed from the store it wraps.
+type HistoryStore struct {
+	Store
+	history *History
+	pending *[]HistoryEntry // the entries of the current batch, nil if none
+}
+
+// Returns a store saving its tasks in the given store and recording their
+// changes in the given history.
+func NewHistoryStore(store Store, history *History) *HistoryStore {
+	return &HistoryStore{Store: store, history: history}
+}
+
+// Returns the task of given full uuid in the wrapped store, nil if there is
+// none.
+func (s *HistoryStore) previous(uuid string) (*Task, error) {
+	tasks, err := s.Store.Load(uuid)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		if t.uuid == uuid {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// Records the given entries, at the end of the batch in a batch. The changes
+// being made, a failure to record them is logged rather than returned.
+func (s *HistoryStore) record(entries []HistoryEntry) {
+	if s.pending != nil {
+		*s.pending = append(*s.pending, entries...)
+		return
+	}
+	if err := s.history.Append(entries); err != nil {
+		logf("could not record the history: %s", err)
+	}
+}
+
+func (s *HistoryStore) Save(t *Task) error {
+	previous, err := s.previous(t.uuid)
+	if err != nil {
+		return err
+	}
+	if err = s.Store.Save(t); err != nil {
+		return err
+	}
+	s.record(historyEntries(previous, t))
+	return nil
+}
+
+func (s *HistoryStore) Remove(uuid string) error {
+	previous, err := s.previous(uuid)
+	if err != nil {
+		return err
+	}
+	if err = s.Store.Remove(uuid); err != nil || previous == nil {
+		return err
+	}
+	s.record([]HistoryEntry{{Time: now(), Actor: Actor, Action: ActionRemove,
+		Uuid: uuid, Title: previous.title}})
+	return nil
+}
+
+// Calls fn with a store whose changes are applied at once if the wrapped
+// store is a Batcher, their entries being recorded if fn returns nil. If the
+// wrapped store is not a Batcher, the changes are recorded as they are made.
+func (s *HistoryStore) Batch(fn func(Store) error) error {
+	if _, ok := s.Store.(Batcher); !ok || s.pending != nil {
+		return fn(s)
+	}
+	var pending []HistoryEntry
+	err := Batch(s.Store, func(inner Store) error {
+		return fn(&HistoryStore{Store: inner, history: s.history,
+			pending: &pending})
+	})
+	if err != nil {
+		return err
+	}
+	s.record(pending)
+	return nil
+}
+
+// Returns the tasks matching the given filters of FilterTasks at the given
+// time, loaded by the wrapped store if it is a FilterStore.
+func (s *HistoryStore) LoadFiltered(filters []string, now time.Time) ([]*Task,
+	error) {
+	return loadFilteredAt(s.Store, filters, now)
+}
+
+// Closes the wrapped store if it is an io.Closer.
+func (s *HistoryStore) Close() error {
+	return closeStore(s.Store)
+}
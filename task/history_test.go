@@ -0,0 +1,118 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Returns a history store wrapping a memory store, its history being kept in
+// a temporary directory.
+func newHistoryStore(t *testing.T) (*HistoryStore, *History) {
+	history := NewHistory(filepath.Join(t.TempDir(), "history"))
+	return NewHistoryStore(NewMemoryStore(), history), history
+}
+
+func TestHistoryStoreRecordsChanges(t *testing.T) {
+	store, history := newHistoryStore(t)
+	ts, _ := NewDefault("test")
+	if err := store.Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetStatus(Doing)
+	ts.SetPriority(High)
+	ts.SetDescription("details")
+	ts.Touch()
+	if err := store.Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	// saving an unchanged task records nothing
+	if err := store.Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := store.Remove(ts.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	entries, err := history.Entries(ts.Uuid()[:4], time.Time{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := []HistoryEntry{
+		{Action: ActionCreate},
+		{Action: ActionUpdate, Field: "description", Old: "", New: "details"},
+		{Action: ActionUpdate, Field: "status", Old: "todo", New: "doing"},
+		{Action: ActionUpdate, Field: "priority", Old: "medium", New: "high"},
+		{Action: ActionRemove},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %v", len(entries), len(want), entries)
+	}
+	for i, entry := range entries {
+		if entry.Action != want[i].Action || entry.Field != want[i].Field ||
+			entry.Old != want[i].Old || entry.New != want[i].New {
+			t.Fatalf("entry %d: got %v, want %v", i, entry, want[i])
+		}
+		if entry.Uuid != ts.Uuid() || entry.Title != "test" ||
+			entry.Actor != Actor || entry.Time.IsZero() {
+			t.Fatalf("entry %d: got %v", i, entry)
+		}
+	}
+}
+
+func TestHistoryStoreRecordsBatchesOnSuccess(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournalStore(filepath.Join(dir, "journal"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	history := NewHistory(filepath.Join(dir, "history"))
+	store := NewHistoryStore(journal, history)
+	ts, _ := NewDefault("test")
+	failure := errors.New("failure")
+	err = Batch(store, func(s Store) error {
+		s.Save(ts)
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+	entries, _ := history.Entries("", time.Time{})
+	if len(entries) != 0 {
+		t.Fatalf("got %d entries, want none", len(entries))
+	}
+	err = Batch(store, func(s Store) error {
+		return s.Save(ts)
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	entries, _ = history.Entries("", time.Time{})
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+}
+
+func TestHistoryEntriesSkipsIncompleteLastLine(t *testing.T) {
+	store, history := newHistoryStore(t)
+	ts, _ := NewDefault("test")
+	store.Save(ts)
+	f, err := os.OpenFile(history.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	f.WriteString(`{"time":"2026-`)
+	f.Close()
+	entries, err := history.Entries("", time.Time{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entries, _ = history.Entries("", time.Now().Add(time.Hour))
+	if len(entries) != 0 {
+		t.Fatalf("got %d entries, want none after the since time", len(entries))
+	}
+}
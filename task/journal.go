@@ -0,0 +1,376 @@
+package task
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	journalCreate = 'c' // a task saved for the first time
+	journalUpdate = 'u' // a task saved again
+	journalRemove = 'r' // a task removed
+)
+
+// The magic value starting a snapshot file, followed by its version.
+var snapshotMagic = []byte{0x00, 'A', 'G', 'S'}
+
+const snapshotVersion = 1
+
+var ErrCorruptJournal = errors.New("corrupt journal")
+
+// A JournalStore keeps its tasks in a single log file where every creation,
+// update and removal of a task is appended as a record. The tasks are rebuilt
+// by replaying the log on top of the last snapshot, a file holding every task
+// at the time it was written. Compact writes a new snapshot and empties the
+// log.
+//
+// A record is made of its kind on one byte, the length of its payload on four
+// bytes, the payload and the CRC-32 of the previous bytes on four bytes. The
+// payload of a creation or an update is the task, encoded in the file format
+// of task files, and the payload of a removal is the uuid of the task.
+type JournalStore struct {
+	path  string            // the path of the log file
+	tasks map[string][]byte // the encoded tasks by uuid
+}
+
+// Opens the journal store whose log file has the given path, its snapshot
+// being at the same path with the ".snapshot" suffix. Missing files are
+// created on the first change. A record partially written at the end of the
+// log, by an interrupted save, is discarded.
+func OpenJournalStore(path string) (*JournalStore, error) {
+	if path == "" {
+		return nil, ErrInvalidLoadPath
+	}
+	s := &JournalStore{path: path, tasks: make(map[string][]byte)}
+	if err := s.readSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Returns the path of the snapshot file of the store.
+func (s *JournalStore) snapshotPath() string {
+	return s.path + ".snapshot"
+}
+
+// Loads the tasks of the snapshot file, if it exists.
+func (s *JournalStore) readSnapshot() error {
+	data, err := os.ReadFile(s.snapshotPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(data, snapshotMagic) ||
+		len(data) < len(snapshotMagic)+1+4 {
+		return fmt.Errorf("%w: invalid snapshot header", ErrCorruptJournal)
+	}
+	if data[len(snapshotMagic)] != snapshotVersion {
+		return ErrUnsupportedVersion
+	}
+	d := decoder{data: data, offset: len(snapshotMagic) + 1}
+	count := int(d.uint32())
+	for i := 0; i < count; i++ {
+		encoded := d.next(int(d.uint32()))
+		if d.err != nil {
+			return fmt.Errorf("%w: truncated snapshot", ErrCorruptJournal)
+		}
+		ts, _, err := decodeTask(encoded)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrCorruptJournal, err)
+		}
+		s.tasks[ts.uuid] = encoded
+	}
+	return nil
+}
+
+// Applies the records of the log file, if it exists. A partially written last
+// record is truncated away so that the following records are appended after
+// the last complete one.
+func (s *JournalStore) replay() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	offset := 0
+	for offset < len(data) {
+		if len(data) < offset+1+4 {
+			break
+		}
+		length := int(binary.BigEndian.Uint32(data[offset+1:]))
+		end := offset + 1 + 4 + length + 4
+		if end > len(data) {
+			break
+		}
+		record := data[offset : end-4]
+		sum := binary.BigEndian.Uint32(data[end-4 : end])
+		if crc32.ChecksumIEEE(record) != sum {
+			return fmt.Errorf("%w: bad checksum at offset %d",
+				ErrCorruptJournal, offset)
+		}
+		if err = s.apply(record[0], record[5:]); err != nil {
+			return fmt.Errorf("%w: record at offset %d: %s",
+				ErrCorruptJournal, offset, err)
+		}
+		offset = end
+	}
+	if offset < len(data) {
+		logf("discarded the incomplete last record of %s", s.path)
+		return os.Truncate(s.path, int64(offset))
+	}
+	return nil
+}
+
+// Applies to the tasks in memory the record of given kind and payload.
+func (s *JournalStore) apply(kind byte, payload []byte) error {
+	switch kind {
+	case journalCreate, journalUpdate:
+		ts, _, err := decodeTask(payload)
+		if err != nil {
+			return err
+		}
+		s.tasks[ts.uuid] = payload
+	case journalRemove:
+		delete(s.tasks, string(payload))
+	default:
+		return errors.New("unknown record kind")
+	}
+	return nil
+}
+
+// Returns the record of given kind and payload.
+func journalRecord(kind byte, payload []byte) []byte {
+	e := encoder{}
+	e.byte(kind)
+	e.uint32(uint32(len(payload)))
+	e.data = append(e.data, payload...)
+	e.uint32(crc32.ChecksumIEEE(e.data))
+	return e.data
+}
+
+// Appends the given records to the log file in a single write and flushes it
+// to disk.
+func (s *JournalStore) append(records []byte) error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(records); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Returns the record saving the given task, and applies it in memory.
+func (s *JournalStore) saveRecord(t *Task) []byte {
+	kind := byte(journalCreate)
+	if _, ok := s.tasks[t.uuid]; ok {
+		kind = journalUpdate
+	}
+	encoded := t.encode()
+	s.tasks[t.uuid] = encoded
+	return journalRecord(kind, encoded)
+}
+
+// Returns the record removing the task of given uuid, and applies it in
+// memory. Returns nil if there is no such task.
+func (s *JournalStore) removeRecord(uuid string) []byte {
+	if _, ok := s.tasks[uuid]; !ok {
+		return nil
+	}
+	delete(s.tasks, uuid)
+	return journalRecord(journalRemove, []byte(uuid))
+}
+
+// Returns the sorted uuids of the tasks of the store having the given prefix.
+func (s *JournalStore) uuids(prefix string) []string {
+	var res []string
+	for uuid := range s.tasks {
+		if strings.HasPrefix(uuid, prefix) {
+			res = append(res, uuid)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Returns the decoded tasks of given uuids.
+func (s *JournalStore) decode(uuids []string) ([]*Task, error) {
+	var tasks []*Task
+	for _, uuid := range uuids {
+		ts, _, err := decodeTask(s.tasks[uuid])
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, ts)
+	}
+	return tasks, nil
+}
+
+func (s *JournalStore) LoadAll() ([]*Task, error) {
+	return s.decode(s.uuids(""))
+}
+
+func (s *JournalStore) Load(prefix string) ([]*Task, error) {
+	if prefix == "" {
+		return nil, ErrInvalidUuid
+	}
+	return s.decode(s.uuids(prefix))
+}
+
+func (s *JournalStore) Save(t *Task) error {
+	previous, existed := s.tasks[t.uuid]
+	if err := s.append(s.saveRecord(t)); err != nil {
+		if existed {
+			s.tasks[t.uuid] = previous
+		} else {
+			delete(s.tasks, t.uuid)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *JournalStore) Remove(uuid string) error {
+	previous := s.tasks[uuid]
+	record := s.removeRecord(uuid)
+	if record == nil {
+		return nil
+	}
+	if err := s.append(record); err != nil {
+		s.tasks[uuid] = previous
+		return err
+	}
+	return nil
+}
+
+func (s *JournalStore) Exists(prefix string) (bool, error) {
+	if prefix == "" {
+		return false, ErrInvalidUuid
+	}
+	return len(s.uuids(prefix)) > 0, nil
+}
+
+// A journalBatch is the store given to the function of JournalStore.Batch. It
+// applies the changes in memory and keeps their records to append them at
+// once.
+type journalBatch struct {
+	*JournalStore
+	records []byte // the records of the changes of the batch
+}
+
+func (b *journalBatch) Save(t *Task) error {
+	b.records = append(b.records, b.saveRecord(t)...)
+	return nil
+}
+
+// Calls fn with the batch itself, the changes of fn being part of the batch.
+func (b *journalBatch) Batch(fn func(Store) error) error {
+	return fn(b)
+}
+
+func (b *journalBatch) Remove(uuid string) error {
+	b.records = append(b.records, b.removeRecord(uuid)...)
+	return nil
+}
+
+// Calls fn with a store whose changes are appended to the log in a single
+// write if fn returns nil, and discarded otherwise.
+func (s *JournalStore) Batch(fn func(Store) error) error {
+	saved := make(map[string][]byte, len(s.tasks))
+	for uuid, encoded := range s.tasks {
+		saved[uuid] = encoded
+	}
+	batch := &journalBatch{JournalStore: s}
+	err := fn(batch)
+	if err == nil && len(batch.records) != 0 {
+		err = s.append(batch.records)
+	}
+	if err != nil {
+		s.tasks = saved
+	}
+	return err
+}
+
+// Writes a snapshot holding every task of the store and empties the log. The
+// snapshot replaces the previous one atomically, so that an interruption
+// leaves either the previous snapshot and the whole log, or the new snapshot
+// and a log whose records are already part of it.
+func (s *JournalStore) Compact() error {
+	e := encoder{}
+	e.data = append(e.data, snapshotMagic...)
+	e.byte(snapshotVersion)
+	uuids := s.uuids("")
+	e.uint32(uint32(len(uuids)))
+	for _, uuid := range uuids {
+		e.uint32(uint32(len(s.tasks[uuid])))
+		e.data = append(e.data, s.tasks[uuid]...)
+	}
+	if err := writeFileAtomic(s.snapshotPath(), e.data, 0644); err != nil {
+		return err
+	}
+	err := os.Truncate(s.path, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Returns the sizes in bytes of the snapshot and of the log files.
+func (s *JournalStore) Sizes() (int64, int64, error) {
+	var sizes [2]int64
+	for i, path := range []string{s.snapshotPath(), s.path} {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		sizes[i] = info.Size()
+	}
+	return sizes[0], sizes[1], nil
+}
+
+// Rewrites in the current format version every task of the store that is
+// stored in an older version. Returns the number of rewritten tasks and the
+// errors of the tasks that could not be converted.
+func (s *JournalStore) Migrate() (int, []*MigrationError, error) {
+	var records []byte
+	var failures []*MigrationError
+	migrated := 0
+	for _, uuid := range s.uuids("") {
+		ts, version, err := decodeTask(s.tasks[uuid])
+		if err != nil {
+			failures = append(failures, &MigrationError{uuid, err})
+			continue
+		}
+		if version < FormatVersion {
+			records = append(records, s.saveRecord(ts)...)
+			migrated++
+		}
+	}
+	if len(records) == 0 {
+		return 0, failures, nil
+	}
+	if err := s.append(records); err != nil {
+		return 0, nil, err
+	}
+	return migrated, failures, nil
+}
@@ -0,0 +1,172 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Returns the path of a journal in a temporary directory removed at the end of
+// the test.
+func newTestJournalPath(t *testing.T) string {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	t.Cleanup(func() { os.RemoveAll(dirname) })
+	return filepath.Join(dirname, "journal")
+}
+
+// Opens the journal store of given path, failing the test on error.
+func openTestJournal(t *testing.T, path string) *JournalStore {
+	store, err := OpenJournalStore(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return store
+}
+
+func TestJournalStoreRebuildsTasksByReplayingTheLog(t *testing.T) {
+	path := newTestJournalPath(t)
+	store := openTestJournal(t, path)
+	kept, _ := NewDefault("kept")
+	removed, _ := NewDefault("removed")
+	for _, ts := range []*Task{kept, removed} {
+		if err := store.Save(ts); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	kept.SetStatus(Done)
+	if err := store.Save(kept); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := store.Remove(removed.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err := openTestJournal(t, path).LoadAll()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 1 || tasks[0].Uuid() != kept.Uuid() {
+		t.Fatalf("expected only %s left", kept.Uuid())
+	}
+	if tasks[0].Status() != Done {
+		t.Fatalf("got %d, want %d", tasks[0].Status(), Done)
+	}
+}
+
+func TestJournalStoreDiscardsIncompleteLastRecord(t *testing.T) {
+	path := newTestJournalPath(t)
+	store := openTestJournal(t, path)
+	first, _ := NewDefault("first")
+	second, _ := NewDefault("second")
+	if err := store.Save(first); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := store.Save(second); err != nil {
+		t.Fatalf(err.Error())
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatalf(err.Error())
+	}
+	store = openTestJournal(t, path)
+	tasks, err := store.LoadAll()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 1 || tasks[0].Uuid() != first.Uuid() {
+		t.Fatalf("expected only %s left", first.Uuid())
+	}
+	if err = store.Save(second); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err = openTestJournal(t, path).LoadAll()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+}
+
+func TestJournalStoreWithBadChecksumReturnsError(t *testing.T) {
+	path := newTestJournalPath(t)
+	ts, _ := NewDefault("test")
+	if err := openTestJournal(t, path).Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	data[10] ^= 0xff
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err = OpenJournalStore(path); !errors.Is(err, ErrCorruptJournal) {
+		t.Fatalf("got %v, want %v", err, ErrCorruptJournal)
+	}
+}
+
+func TestJournalStoreCompactKeepsTasksAndEmptiesTheLog(t *testing.T) {
+	path := newTestJournalPath(t)
+	store := openTestJournal(t, path)
+	for _, title := range []string{"a", "b", "c"} {
+		ts, _ := NewDefault(title)
+		if err := store.Save(ts); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	snapshot, log, err := store.Sizes()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if snapshot == 0 || log != 0 {
+		t.Fatalf("got sizes %d and %d, want a snapshot and an empty log",
+			snapshot, log)
+	}
+	ts, _ := NewDefault("d")
+	if err = store.Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err := openTestJournal(t, path).LoadAll()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 4 {
+		t.Fatalf("got %d tasks, want 4", len(tasks))
+	}
+}
+
+func TestJournalStoreBatchIsDiscardedOnError(t *testing.T) {
+	path := newTestJournalPath(t)
+	store := openTestJournal(t, path)
+	failure := errors.New("failure")
+	err := Batch(store, func(s Store) error {
+		ts, _ := NewDefault("test")
+		if err := s.Save(ts); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+	for _, s := range []*JournalStore{store, openTestJournal(t, path)} {
+		tasks, err := s.LoadAll()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if len(tasks) != 0 {
+			t.Fatalf("got %d tasks, want 0", len(tasks))
+		}
+	}
+}
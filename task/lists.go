@@ -0,0 +1,127 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInvalidListName = errors.New("invalid list name")
+	ErrTaskExists      = errors.New("a task of same uuid already exists")
+)
+
+// Returns nil if the given name can name a list, that is if it is not empty
+// and can be the name of a directory.
+func CheckListName(name string) error {
+	if name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("%w: %s", ErrInvalidListName, name)
+	}
+	return nil
+}
+
+// Returns the sorted names of the lists stored in the given directory, that
+// is of its subdirectories whose name can name a list. A missing directory
+// holds no list.
+func ListNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && CheckListName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Moves the tasks of the store from whose uuids have the given prefixes to the
+// store to, and returns them. Every prefix must denote exactly one task, and no
+// task of the store to must have the uuid of a moved task, otherwise no task is
+// moved. The relations of the moved tasks to tasks that are neither moved nor
+// in the store to are dropped, see dropMissingRelations.
+func MoveTasks(from, to Store, prefixes []string) ([]*Task, error) {
+	var tasks []*Task
+	moved := make(map[string]bool)
+	for _, prefix := range prefixes {
+		t, err := LoadUnique(from, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", prefix, err)
+		}
+		if exists, err := to.Exists(t.uuid); err != nil {
+			return nil, err
+		} else if exists {
+			return nil, fmt.Errorf("%s: %w", t.uuid, ErrTaskExists)
+		}
+		if !moved[t.uuid] {
+			moved[t.uuid] = true
+			tasks = append(tasks, t)
+		}
+	}
+	for _, t := range tasks {
+		if err := dropMissingRelations(to, t, moved); err != nil {
+			return nil, err
+		}
+	}
+	// the tasks are saved in the destination before being removed, so that an
+	// interruption leaves them in both stores rather than in none
+	err := Batch(to, func(s Store) error {
+		for _, t := range tasks {
+			if err := s.Save(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = Batch(from, func(s Store) error {
+		for _, t := range tasks {
+			if err := s.Remove(t.uuid); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Drops the parent and the dependencies of the given task, about to be saved
+// in the given store, that the store does not hold and whose uuid is not in
+// kept, and its parent if it would make a cycle. Every dropped relation is
+// logged.
+func dropMissingRelations(store Store, t *Task, kept map[string]bool) error {
+	if t.parent != "" && !kept[t.parent] {
+		if exists, err := store.Exists(t.parent); err != nil {
+			return err
+		} else if !exists {
+			logf("%s no longer has missing parent %s", t.uuid, t.parent)
+			t.parent = ""
+		}
+	}
+	if err := dropCyclicParent(store, t); err != nil {
+		return err
+	}
+	for _, dep := range t.Dependencies() {
+		if kept[dep] {
+			continue
+		}
+		if exists, err := store.Exists(dep); err != nil {
+			return err
+		} else if !exists {
+			logf("%s no longer depends on missing task %s", t.uuid, dep)
+			t.RemoveDependency(dep)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckListNameRejectsPaths(t *testing.T) {
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`, "-x"} {
+		if err := CheckListName(name); !errors.Is(err, ErrInvalidListName) {
+			t.Fatalf("%q: got %v, want %v", name, err, ErrInvalidListName)
+		}
+	}
+	if err := CheckListName("work"); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestListNamesReturnsValidDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"work", "home", "-bad"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	names, err := ListNames(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if want := []string{"home", "work"}; !slices.Equal(names, want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	names, err = ListNames(filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(names) != 0 {
+		t.Fatalf("got %v, want no list", names)
+	}
+}
+
+func TestMoveTasksDropsRelationsLeftBehind(t *testing.T) {
+	from := NewMemoryStore()
+	to := NewMemoryStore()
+	parent := newSubtask(t, "parent", Todo, nil)
+	child := newSubtask(t, "child", Todo, parent)
+	dep := newSubtask(t, "dep", Todo, nil)
+	moved := newSubtask(t, "moved", Todo, parent)
+	moved.AddDependency(dep.Uuid())
+	moved.AddDependency(child.Uuid())
+	for _, ts := range []*Task{parent, child, dep, moved} {
+		from.Save(ts)
+	}
+	tasks, err := MoveTasks(from, to, []string{moved.Uuid(), child.Uuid()})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d moved tasks, want 2", len(tasks))
+	}
+	got, err := LoadUnique(to, moved.Uuid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if got.Parent() != "" {
+		t.Fatalf("got parent %v, want none", got.Parent())
+	}
+	if deps := got.Dependencies(); !slices.Equal(deps, []string{child.Uuid()}) {
+		t.Fatalf("got %v, want [%v]", deps, child.Uuid())
+	}
+	if exists, _ := from.Exists(moved.Uuid()); exists {
+		t.Fatalf("moved task still in the source store")
+	}
+}
+
+func TestMoveTasksRefusesUuidCollisions(t *testing.T) {
+	from := NewMemoryStore()
+	to := NewMemoryStore()
+	ts := newSubtask(t, "task", Todo, nil)
+	other := newSubtask(t, "other", Todo, nil)
+	from.Save(ts)
+	from.Save(other)
+	existing := *ts
+	existing.title = "existing"
+	to.Save(&existing)
+	_, err := MoveTasks(from, to, []string{other.Uuid(), ts.Uuid()})
+	if !errors.Is(err, ErrTaskExists) {
+		t.Fatalf("got %v, want %v", err, ErrTaskExists)
+	}
+	if kept, _ := LoadUnique(to, ts.Uuid()); kept.Title() != "existing" {
+		t.Fatalf("got %v, want the existing task kept", kept.Title())
+	}
+	if exists, _ := to.Exists(other.Uuid()); exists {
+		t.Fatalf("task moved despite the collision")
+	}
+}
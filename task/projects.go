@@ -0,0 +1,131 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const ProjectMaxLength = 255
+
+var ErrInvalidProject = errors.New("project must be dot separated names of at most 255 characters, without spaces")
+
+// Returns true if the given project path is valid: it is at most
+// ProjectMaxLength bytes long and made of non-empty names separated by dots,
+// holding no space nor comma.
+func IsValidProject(project string) bool {
+	if project == "" || len(project) > ProjectMaxLength {
+		return false
+	}
+	for _, name := range strings.Split(project, ".") {
+		if name == "" || strings.ContainsFunc(name, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if the given project is the given ancestor or one of its
+// subprojects.
+func isInProject(project, ancestor string) bool {
+	return project == ancestor || strings.HasPrefix(project, ancestor+".")
+}
+
+// Parses the strings and returns the projects of the "project:path" filters
+// found, without duplicates
+func ParseProjectFiltersFrom(strs []string) []string {
+	var res []string
+	for _, str := range strs {
+		project, ok := strings.CutPrefix(str, "project:")
+		if ok && IsValidProject(project) && !slices.Contains(res, project) {
+			res = append(res, project)
+		}
+	}
+	return res
+}
+
+// Returns true if the task is in one of the given projects or in one of their
+// subprojects
+func matchesProjectFilters(t *Task, projects []string) bool {
+	for _, project := range projects {
+		if isInProject(t.project, project) {
+			return true
+		}
+	}
+	return false
+}
+
+// A ProjectNode is a project of a project tree, with the number of tasks of
+// every status of the project and of its subprojects.
+type ProjectNode struct {
+	Name     string         // the last name of the project path
+	Path     string         // the project path
+	Todo     int            // the number of tasks to do
+	Doing    int            // the number of tasks being done
+	Done     int            // the number of tasks done
+	Children []*ProjectNode // the subprojects, sorted by name
+}
+
+// Returns the number of tasks of the project and of its subprojects.
+func (n *ProjectNode) Total() int {
+	return n.Todo + n.Doing + n.Done
+}
+
+// Returns the percentage of done tasks of the project and of its subprojects,
+// rounded down.
+func (n *ProjectNode) Completion() int {
+	if n.Total() == 0 {
+		return 0
+	}
+	return n.Done * 100 / n.Total()
+}
+
+// Returns the child of the node of given name, added if it does not exist.
+func (n *ProjectNode) child(name string) *ProjectNode {
+	i, found := slices.BinarySearchFunc(n.Children, name,
+		func(c *ProjectNode, name string) int {
+			return strings.Compare(c.Name, name)
+		})
+	if !found {
+		path := name
+		if n.Path != "" {
+			path = n.Path + "." + name
+		}
+		n.Children = slices.Insert(n.Children, i,
+			&ProjectNode{Name: name, Path: path})
+	}
+	return n.Children[i]
+}
+
+// Adds the given status to the counts of the node.
+func (n *ProjectNode) count(status byte) {
+	switch status {
+	case Todo:
+		n.Todo++
+	case Doing:
+		n.Doing++
+	default:
+		n.Done++
+	}
+}
+
+// Returns the tree of the projects of the given tasks, as the sorted top level
+// projects. Every node counts the tasks of its project and of its
+// subprojects. Tasks without project are not counted.
+func ProjectTree(tasks []*Task) []*ProjectNode {
+	root := &ProjectNode{}
+	for _, t := range tasks {
+		if t.project == "" {
+			continue
+		}
+		node := root
+		for _, name := range strings.Split(t.project, ".") {
+			node = node.child(name)
+			node.count(t.status)
+		}
+	}
+	return root.Children
+}
@@ -0,0 +1,120 @@
+package task
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIsValidProject(t *testing.T) {
+	valids := []string{"infra", "infra.ci.runners", "release-1_4"}
+	for _, project := range valids {
+		if !IsValidProject(project) {
+			t.Fatalf("%q: expected valid project", project)
+		}
+	}
+	invalids := []string{"", ".infra", "infra.", "infra..ci", "in fra", "a,b"}
+	for _, project := range invalids {
+		if IsValidProject(project) {
+			t.Fatalf("%q: expected invalid project", project)
+		}
+	}
+}
+
+func TestSetInvalidProjectReturnsError(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = ts.SetProject("infra..ci"); err != ErrInvalidProject {
+		t.Fatalf("got %v, want %v", err, ErrInvalidProject)
+	}
+	if err = ts.SetProject(""); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestFilterTasksByProject(t *testing.T) {
+	infra, _ := NewDefault("infra")
+	infra.SetProject("infra")
+	runners, _ := NewDefault("runners")
+	runners.SetProject("infra.ci.runners")
+	infrastructure, _ := NewDefault("infrastructure")
+	infrastructure.SetProject("infrastructure")
+	none, _ := NewDefault("none")
+	tasks := []*Task{infra, runners, infrastructure, none}
+	tests := []struct {
+		filters []string
+		want    []*Task
+	}{
+		{[]string{"project:infra"}, []*Task{infra, runners}},
+		{[]string{"project:infra.ci"}, []*Task{runners}},
+		{[]string{"project:infra.ci", "project:infrastructure"},
+			[]*Task{runners, infrastructure}},
+		{[]string{"project:infra.c"}, nil},
+	}
+	for _, test := range tests {
+		got, err := FilterTasks(tasks, test.filters)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !slices.Equal(got, test.want) {
+			t.Fatalf("%v: got %d tasks, want %d", test.filters, len(got),
+				len(test.want))
+		}
+	}
+}
+
+func TestProjectTreeCountsSubprojects(t *testing.T) {
+	var tasks []*Task
+	for _, p := range []struct {
+		project string
+		status  byte
+	}{
+		{"infra", Todo},
+		{"infra.ci.runners", Done},
+		{"infra.ci.runners", Doing},
+		{"infra.ci", Done},
+		{"home", Done},
+		{"", Todo},
+	} {
+		ts, _ := NewTask("test", "", false, Medium, p.status)
+		ts.SetProject(p.project)
+		tasks = append(tasks, ts)
+	}
+	tree := ProjectTree(tasks)
+	if len(tree) != 2 || tree[0].Name != "home" || tree[1].Name != "infra" {
+		t.Fatalf("got %d top level projects, want home and infra", len(tree))
+	}
+	infra := tree[1]
+	if infra.Todo != 1 || infra.Doing != 1 || infra.Done != 2 {
+		t.Fatalf("got %d/%d/%d, want 1/1/2", infra.Todo, infra.Doing,
+			infra.Done)
+	}
+	if infra.Completion() != 50 {
+		t.Fatalf("got %d%%, want 50%%", infra.Completion())
+	}
+	ci := infra.Children[0]
+	if ci.Path != "infra.ci" || ci.Total() != 3 {
+		t.Fatalf("got %s with %d tasks, want infra.ci with 3", ci.Path,
+			ci.Total())
+	}
+	runners := ci.Children[0]
+	if runners.Path != "infra.ci.runners" || runners.Completion() != 50 ||
+		len(runners.Children) != 0 {
+		t.Fatalf("got %s at %d%%, want infra.ci.runners at 50%%",
+			runners.Path, runners.Completion())
+	}
+	if tree[0].Completion() != 100 {
+		t.Fatalf("got %d%%, want 100%%", tree[0].Completion())
+	}
+}
+
+func TestDisplayOfTaskWithProjectShowsProject(t *testing.T) {
+	ts, _ := NewDefault("test")
+	ts.SetProject("infra.ci")
+	ts.AddTag("backend")
+	exp := "[To do] test @infra.ci +backend <medium> " + ts.Uuid()
+	if ts.Display() != exp {
+		t.Fatalf("got \"%s\", want \"%s\"", ts.Display(), exp)
+	}
+}
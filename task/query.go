@@ -0,0 +1,617 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A QueryError reports a query that could not be parsed.
+type QueryError struct {
+	Column int    // the column of the error in the query, starting at 1
+	Msg    string // the description of the error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+const (
+	tokenWord = iota
+	tokenString
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenEnd
+)
+
+// A token is a lexical unit of a query.
+type token struct {
+	kind   int    // tokenWord, tokenString, tokenOperator, tokenOpen, ...
+	text   string // the text of the token, unquoted for strings
+	column int    // the column of the first character of the token
+}
+
+// Returns true if the given byte is part of a comparison operator.
+func isOperatorByte(b byte) bool {
+	return strings.IndexByte("=!<>~", b) >= 0
+}
+
+// Returns true if the given byte ends a word.
+func endsWord(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '(' || b == ')' ||
+		b == '"' || isOperatorByte(b)
+}
+
+// Splits the given query into tokens, the last one being of kind tokenEnd.
+func lexQuery(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		b := query[i]
+		switch {
+		case b == ' ' || b == '\t' || b == '\n':
+			i++
+		case b == '(':
+			tokens = append(tokens, token{tokenOpen, "(", i + 1})
+			i++
+		case b == ')':
+			tokens = append(tokens, token{tokenClose, ")", i + 1})
+			i++
+		case b == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(query) && query[j] != '"'; j++ {
+				if query[j] == '\\' && j+1 < len(query) {
+					j++
+				}
+				sb.WriteByte(query[j])
+			}
+			if j == len(query) {
+				return nil, &QueryError{i + 1, "unterminated string"}
+			}
+			tokens = append(tokens, token{tokenString, sb.String(), i + 1})
+			i = j + 1
+		case isOperatorByte(b):
+			op := query[i : i+1]
+			if i+1 < len(query) {
+				switch query[i : i+2] {
+				case "!=", "!~", "<=", ">=":
+					op = query[i : i+2]
+				}
+			}
+			if op == "!" {
+				return nil, &QueryError{i + 1, "unexpected !"}
+			}
+			tokens = append(tokens, token{tokenOperator, op, i + 1})
+			i += len(op)
+		default:
+			j := freeWordEnd(query, i)
+			if j == i {
+				for j < len(query) && !endsWord(query[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, token{tokenWord, query[i:j], i + 1})
+			i = j
+		}
+	}
+	return append(tokens, token{tokenEnd, "", len(query) + 1}), nil
+}
+
+// Returns the end of the tag or project filter word starting at the given
+// index of the given query, or the index itself if no such word starts there.
+// Tags and projects can hold the characters ending other words, so these words
+// end at the first space only, but for the closing parentheses ending them
+// that are not balanced by opening ones, such as the last one of "(+a or +b)".
+func freeWordEnd(query string, i int) int {
+	start := i
+	switch {
+	case strings.HasPrefix(query[i:], "project:"):
+		start += len("project:")
+	case query[i] == '+' || query[i] == '-':
+		start++
+	default:
+		return i
+	}
+	j := start
+	for j < len(query) && query[j] != ' ' && query[j] != '\t' &&
+		query[j] != '\n' {
+		j++
+	}
+	open := strings.Count(query[start:j], "(")
+	closing := strings.Count(query[start:j], ")")
+	for j > start && query[j-1] == ')' && closing > open {
+		j--
+		closing--
+	}
+	if j == start {
+		return i
+	}
+	return j
+}
+
+// A queryNode is a node of the syntax tree of a query.
+type queryNode interface {
+	// Returns true if the task matches the node in the given context.
+	match(t *Task, ctx *queryContext) bool
+}
+
+// A queryContext holds what the nodes of a query need to match tasks.
+type queryContext struct {
+	now   time.Time        // the time the due filters are evaluated at
+	graph *DependencyGraph // the graph the dependencies are looked up in
+}
+
+// An orNode matches the tasks matching one of its nodes.
+type orNode []queryNode
+
+func (n orNode) match(t *Task, ctx *queryContext) bool {
+	for _, node := range n {
+		if node.match(t, ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// An andNode matches the tasks matching all its nodes.
+type andNode []queryNode
+
+func (n andNode) match(t *Task, ctx *queryContext) bool {
+	for _, node := range n {
+		if !node.match(t, ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// A notNode matches the tasks not matching its node.
+type notNode struct {
+	node queryNode
+}
+
+func (n notNode) match(t *Task, ctx *queryContext) bool {
+	return !n.node.match(t, ctx)
+}
+
+// A wordsNode matches the tasks matching bare word filters given next to each
+// other, with the semantics of FilterTasks.
+type wordsNode struct {
+	filters *wordFilters
+}
+
+func (n wordsNode) match(t *Task, ctx *queryContext) bool {
+	return n.filters.match(t, ctx.graph, ctx.now)
+}
+
+// A compareNode matches the tasks whose field compares to a value.
+type compareNode struct {
+	field string    // the compared field
+	op    string    // the comparison operator
+	value string    // the value, lowercased for case-insensitive fields
+	rank  byte      // the status or priority value
+	when  time.Time // the time value of a time field, zero for "none"
+}
+
+// Returns true if the given comparison result, as returned by strings.Compare,
+// satisfies the given ordering operator.
+func compareResult(c int, op string) bool {
+	switch op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// Returns true if the given text, lowercased, matches the value with the
+// given text operator.
+func matchText(text, op, value string) bool {
+	text = strings.ToLower(text)
+	switch op {
+	case "=":
+		return text == value
+	case "!=":
+		return text != value
+	case "~":
+		return strings.Contains(text, value)
+	default:
+		return !strings.Contains(text, value)
+	}
+}
+
+func (n compareNode) match(t *Task, ctx *queryContext) bool {
+	switch n.field {
+	case "status":
+		return compareResult(int(t.status)-int(n.rank), n.op)
+	case "priority":
+		return compareResult(int(t.priority)-int(n.rank), n.op)
+	case "due", "created", "modified", "started", "completed":
+		when := t.timeOf(n.field)
+		if n.when.IsZero() {
+			return when.IsZero() == (n.op == "=")
+		}
+		if when.IsZero() {
+			return false
+		}
+		if isDateOnly(n.when) {
+			when = startOfDay(when)
+		}
+		return compareResult(when.Compare(n.when), n.op)
+	case "title":
+		return matchText(t.title, n.op, n.value)
+	case "desc":
+		return matchText(t.desc, n.op, n.value)
+	case "project":
+		switch n.op {
+		case "=":
+			return t.project != "" && isInProject(t.project, n.value)
+		case "!=":
+			return t.project == "" || !isInProject(t.project, n.value)
+		}
+		return matchText(t.project, n.op, n.value)
+	default:
+		found := false
+		for _, tag := range t.tags {
+			if n.op == "=" || n.op == "!=" {
+				found = found || tag == n.value
+			} else {
+				found = found || strings.Contains(strings.ToLower(tag), n.value)
+			}
+		}
+		return found == (n.op == "=" || n.op == "~")
+	}
+}
+
+// The operators accepted by the fields of comparisons.
+var fieldOperators = map[string][]string{
+	"status":    {"=", "!=", "<", "<=", ">", ">="},
+	"priority":  {"=", "!=", "<", "<=", ">", ">="},
+	"due":       {"=", "!=", "<", "<=", ">", ">="},
+	"created":   {"=", "!=", "<", "<=", ">", ">="},
+	"modified":  {"=", "!=", "<", "<=", ">", ">="},
+	"started":   {"=", "!=", "<", "<=", ">", ">="},
+	"completed": {"=", "!=", "<", "<=", ">", ">="},
+	"title":     {"=", "!=", "~", "!~"},
+	"desc":      {"=", "!=", "~", "!~"},
+	"project":   {"=", "!=", "~", "!~"},
+	"tag":       {"=", "!=", "~", "!~"},
+}
+
+// A queryParser builds the syntax tree of a query from its tokens.
+type queryParser struct {
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+// Returns the next token without consuming it.
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// Consumes and returns the next token.
+func (p *queryParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEnd {
+		p.pos++
+	}
+	return tok
+}
+
+// Returns true if the given token is the given keyword.
+func isKeyword(tok token, keyword string) bool {
+	return tok.kind == tokenWord && tok.text == keyword
+}
+
+// Parses terms separated by "or".
+func (p *queryParser) parseOr() (queryNode, error) {
+	var nodes orNode
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if !isKeyword(p.peek(), "or") {
+			break
+		}
+		p.next()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+// Parses groups separated by "and".
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var nodes andNode
+	for {
+		node, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if !isKeyword(p.peek(), "and") {
+			break
+		}
+		p.next()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+// Returns true if the given token can start a term.
+func startsTerm(tok token) bool {
+	return tok.kind == tokenOpen || (tok.kind == tokenWord &&
+		tok.text != "and" && tok.text != "or")
+}
+
+// Parses terms written next to each other. The bare word filters among them
+// are combined as FilterTasks does, and the other terms must all match.
+func (p *queryParser) parseGroup() (queryNode, error) {
+	if !startsTerm(p.peek()) {
+		return nil, p.unexpected()
+	}
+	var words []string
+	var nodes andNode
+	for startsTerm(p.peek()) {
+		tok := p.peek()
+		if tok.kind == tokenWord && p.tokens[p.pos+1].kind != tokenOperator &&
+			tok.text != "not" {
+			if !isFilterWord(tok.text) {
+				return nil, &QueryError{tok.column, "unknown filter " + tok.text}
+			}
+			words = append(words, p.next().text)
+			continue
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(words) != 0 {
+		filters, err := parseWordFilters(words)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, wordsNode{filters})
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+// Parses a term, possibly negated.
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if isKeyword(p.peek(), "not") {
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+// Parses a parenthesized query, a comparison or a bare word filter.
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.peek()
+	if !startsTerm(tok) || isKeyword(tok, "not") {
+		return nil, p.unexpected()
+	}
+	p.next()
+	switch {
+	case tok.kind == tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenClose {
+			return nil, &QueryError{p.peek().column, "expected )"}
+		}
+		p.next()
+		return node, nil
+	case p.peek().kind == tokenOperator:
+		return p.parseComparison(tok)
+	case isFilterWord(tok.text):
+		filters, err := parseWordFilters([]string{tok.text})
+		if err != nil {
+			return nil, err
+		}
+		return wordsNode{filters}, nil
+	default:
+		return nil, &QueryError{tok.column, "unknown filter " + tok.text}
+	}
+}
+
+// Parses the operator and the value of the comparison of the given field.
+func (p *queryParser) parseComparison(field token) (queryNode, error) {
+	operators, ok := fieldOperators[field.text]
+	if !ok {
+		return nil, &QueryError{field.column, "unknown field " + field.text}
+	}
+	op := p.next()
+	if !slices.Contains(operators, op.text) {
+		return nil, &QueryError{op.column, "operator " + op.text +
+			" not supported by " + field.text}
+	}
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, &QueryError{value.column, "expected a value"}
+	}
+	node := compareNode{field: field.text, op: op.text,
+		value: strings.ToLower(value.text)}
+	invalid := &QueryError{value.column, "invalid " + field.text + " " +
+		value.text}
+	switch field.text {
+	case "status":
+		status, err := ParseStatus(value.text)
+		if err != nil {
+			return nil, invalid
+		}
+		node.rank = status
+	case "priority":
+		priority, err := ParsePriority(value.text)
+		if err != nil {
+			return nil, invalid
+		}
+		node.rank = priority
+	case "due", "created", "modified", "started", "completed":
+		if value.text == "none" {
+			if op.text != "=" && op.text != "!=" {
+				return nil, &QueryError{op.column, "operator " + op.text +
+					" not supported by " + field.text + "=none"}
+			}
+			break
+		}
+		when, err := ParseDue(value.text, p.now)
+		if err != nil {
+			return nil, invalid
+		}
+		node.when = when
+	case "project":
+		if op.text == "=" || op.text == "!=" {
+			if !IsValidProject(value.text) {
+				return nil, invalid
+			}
+			// project paths are case-sensitive
+			node.value = value.text
+		}
+	case "tag":
+		if op.text == "=" || op.text == "!=" {
+			if !IsValidTag(value.text) {
+				return nil, invalid
+			}
+			// tags are case-sensitive
+			node.value = value.text
+		}
+	}
+	return node, nil
+}
+
+// Returns the error of an unexpected next token.
+func (p *queryParser) unexpected() error {
+	tok := p.peek()
+	if tok.kind == tokenEnd {
+		return &QueryError{tok.column, "unexpected end of query"}
+	}
+	return &QueryError{tok.column, "unexpected " + tok.text}
+}
+
+// A Query selects tasks. See ParseQuery for its syntax.
+type Query struct {
+	root  queryNode // the syntax tree, nil if the query is empty
+	words []string  // the filters of a query made of bare words only
+	now   time.Time // the time the due filters are evaluated at
+}
+
+// Parses the given query, evaluating the relative dates and the due filters at
+// the given time.
+//
+// A query is made of terms combined with "and", "or", "not" and parentheses,
+// "not" binding tighter than "and", itself binding tighter than "or". A term is
+// either a filter of FilterTasks ("todo", "high", "overdue", "+backend",
+// "project:infra", "ready", "completed:last-week", ...) or a comparison of a
+// field to a value:
+//
+//	status, priority, due  =, !=, <, <=, >, >=
+//	created, modified,     =, !=, <, <=, >, >=
+//	started, completed
+//	title, desc            =, !=, ~ (contains), !~, all case-insensitive
+//	project                = (in the project or a subproject), !=, ~, !~
+//	tag                    = (has the tag), !=, ~ (a tag contains), !~
+//
+// Values holding spaces are written between double quotes. Times are the due
+// dates of ParseDue, a date without time comparing to the day of the times,
+// and "none" compares to the tasks without the time.
+//
+// Terms written next to each other without operator form a group: its bare
+// word filters are combined as FilterTasks does, a union within a category and
+// an intersection across categories, and its other terms must all match. So
+// "todo doing high" keeps its historical meaning.
+//
+// Example:
+//
+//	(todo or doing) and priority>=medium and title~"deploy" and due<2026-11-01
+func ParseQuery(query string, now time.Time) (*Query, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{now: now}
+	if len(tokens) == 1 {
+		return q, nil
+	}
+	p := &queryParser{tokens: tokens, now: now}
+	if q.root, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEnd {
+		return nil, p.unexpected()
+	}
+	if _, ok := q.root.(wordsNode); ok {
+		// without parentheses, the query is the list of its words
+		for _, tok := range tokens[:len(tokens)-1] {
+			if tok.kind != tokenWord {
+				q.words = nil
+				break
+			}
+			q.words = append(q.words, tok.text)
+		}
+	}
+	return q, nil
+}
+
+// Returns the given tasks matching the query. The dependencies are looked up
+// among the given tasks.
+func (q *Query) Filter(tasks []*Task) []*Task {
+	if q.root == nil {
+		return tasks
+	}
+	ctx := &queryContext{now: q.now, graph: NewDependencyGraph(tasks)}
+	var res []*Task
+	for _, t := range tasks {
+		if q.root.match(t, ctx) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// Returns the tasks of the given store matching the given query, parsed by
+// ParseQuery at the current time. A query made of bare word filters only is
+// given to the store as filters of FilterTasks, so that stores filtering tasks
+// on their own are used. Any other query, holding comparisons, "and", "or",
+// "not" or parentheses, is matched against every task of the store, so that
+// the indexed queries of a SQLiteStore do not apply to it.
+func LoadQuery(store Store, query string) ([]*Task, error) {
+	q, err := ParseQuery(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if q.root == nil || q.words != nil {
+		return LoadFiltered(store, q.words)
+	}
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	return q.Filter(tasks), nil
+}
@@ -0,0 +1,188 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Returns the tasks used by the query tests, by title.
+func newQueryTasks(t *testing.T) map[string]*Task {
+	tasks := make(map[string]*Task)
+	for _, p := range []struct {
+		title    string
+		priority byte
+		status   byte
+		due      time.Time
+	}{
+		{"Deploy api", High, Todo,
+			time.Date(2026, time.October, 30, 15, 0, 0, 0, time.Local)},
+		{"deploy web", Medium, Doing,
+			time.Date(2026, time.November, 1, 0, 0, 0, 0, time.Local)},
+		{"write docs", Low, Todo, time.Time{}},
+		{"release", High, Done, time.Time{}},
+	} {
+		ts, err := NewTask(p.title, "", false, p.priority, p.status)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		ts.SetDue(p.due)
+		tasks[p.title] = ts
+	}
+	tasks["write docs"].AddTag("docs")
+	tasks["Deploy api"].SetProject("infra.api")
+	return tasks
+}
+
+// Returns the titles of the given tasks, sorted.
+func sortedTitles(tasks []*Task) []string {
+	var titles []string
+	for _, ts := range tasks {
+		titles = append(titles, ts.Title())
+	}
+	slices.Sort(titles)
+	return titles
+}
+
+func TestQueryFilter(t *testing.T) {
+	byTitle := newQueryTasks(t)
+	var tasks []*Task
+	for _, ts := range byTitle {
+		tasks = append(tasks, ts)
+	}
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Deploy api", "deploy web", "release", "write docs"}},
+		{"todo doing high", []string{"Deploy api"}},
+		{"todo or doing", []string{"Deploy api", "deploy web", "write docs"}},
+		{"priority>=medium and not done", []string{"Deploy api", "deploy web"}},
+		{`title~"deploy"`, []string{"Deploy api", "deploy web"}},
+		{"title=RELEASE", []string{"release"}},
+		{"title!~deploy todo", []string{"write docs"}},
+		{"due<2026-11-01", []string{"Deploy api"}},
+		{"due<=2026-11-01", []string{"Deploy api", "deploy web"}},
+		{"due=2026-10-30", []string{"Deploy api"}},
+		{"due=none", []string{"release", "write docs"}},
+		{"due!=none and overdue", nil},
+		{"(high or +docs) and status<done", []string{"Deploy api",
+			"write docs"}},
+		{"not (high or low)", []string{"deploy web"}},
+		{"tag=docs or project=infra", []string{"Deploy api", "write docs"}},
+		{"project!=infra tag!=docs", []string{"deploy web", "release"}},
+	}
+	for _, test := range tests {
+		q, err := ParseQuery(test.query, now)
+		if err != nil {
+			t.Fatalf("%s: %s", test.query, err.Error())
+		}
+		got := sortedTitles(q.Filter(tasks))
+		if !slices.Equal(got, test.want) {
+			t.Fatalf("%s: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseQueryErrorsGiveTheColumn(t *testing.T) {
+	tests := []struct {
+		query  string
+		column int
+		msg    string
+	}{
+		{"todo and", 9, "unexpected end of query"},
+		{"(todo or done", 14, "expected )"},
+		{"todo unknown", 6, "unknown filter unknown"},
+		{"size>3", 1, "unknown field size"},
+		{"title<b", 6, "operator < not supported by title"},
+		{"priority=urgent", 10, "invalid priority urgent"},
+		{`title~"deploy`, 7, "unterminated string"},
+		{"todo ! done", 6, "unexpected !"},
+		{"or todo", 1, "unexpected or"},
+		{"due>none", 4, "operator > not supported by due=none"},
+		{"todo)", 5, "unexpected )"},
+	}
+	for _, test := range tests {
+		_, err := ParseQuery(test.query, time.Now())
+		var qErr *QueryError
+		if !errors.As(err, &qErr) {
+			t.Fatalf("%s: got %v, want a query error", test.query, err)
+		}
+		if qErr.Column != test.column || qErr.Msg != test.msg {
+			t.Fatalf("%s: got %s, want column %d: %s", test.query,
+				qErr.Error(), test.column, test.msg)
+		}
+	}
+}
+
+func TestParseQueryOfBareWordsKeepsTheWords(t *testing.T) {
+	q, err := ParseQuery("todo high +backend", time.Now())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := []string{"todo", "high", "+backend"}
+	if !slices.Equal(q.words, want) {
+		t.Fatalf("got %v, want %v", q.words, want)
+	}
+	q, err = ParseQuery("(todo high)", time.Now())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if q.words != nil {
+		t.Fatalf("got %v, want no words", q.words)
+	}
+}
+
+func TestLoadQueryFromSQLiteStore(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	for _, ts := range newQueryTasks(t) {
+		if err := store.Save(ts); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	for query, want := range map[string][]string{
+		"todo high":                   {"Deploy api"},
+		"high and title~api or +docs": {"Deploy api", "write docs"},
+	} {
+		tasks, err := LoadQuery(store, query)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if got := sortedTitles(tasks); !slices.Equal(got, want) {
+			t.Fatalf("%s: got %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestQueryTagAndProjectFiltersHoldOperatorCharacters(t *testing.T) {
+	a, _ := NewDefault("a")
+	a.AddTag("a=b")
+	a.SetProject("ops(eu)")
+	x, _ := NewDefault("x")
+	x.AddTag("(x)")
+	none, _ := NewDefault("none")
+	tasks := []*Task{a, x, none}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"+a=b", []string{"a"}},
+		{"+(x)", []string{"x"}},
+		{"-a=b -(x)", []string{"none"}},
+		{"project:ops(eu)", []string{"a"}},
+		{"(+a=b or +(x))", []string{"a", "x"}},
+		{"not (+(x))", []string{"a", "none"}},
+	}
+	for _, test := range tests {
+		q, err := ParseQuery(test.query, time.Now())
+		if err != nil {
+			t.Fatalf("%s: %s", test.query, err.Error())
+		}
+		got := sortedTitles(q.Filter(tasks))
+		if !slices.Equal(got, test.want) {
+			t.Fatalf("%s: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
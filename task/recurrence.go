@@ -0,0 +1,194 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RecurNone = iota
+	RecurDaily
+	RecurWeekly
+	RecurMonthly
+	RecurEvery
+)
+
+var ErrInvalidRecurrence = errors.New("not a valid recurrence rule")
+
+// The names of the week days, indexed by time.Weekday, as written in weekly
+// recurrence rules.
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// A Recurrence is the rule that gives the next occurrences of a periodic task.
+type Recurrence struct {
+	kind     byte   // RecurNone, RecurDaily, RecurWeekly, RecurMonthly or RecurEvery
+	weekdays byte   // bit i is set if the task recurs on time.Weekday(i)
+	monthDay byte   // the day of the month the task recurs on (1 to 31)
+	interval uint16 // the number of days between two occurrences
+}
+
+// Returns a rule recurring every day.
+func Daily() Recurrence {
+	return Recurrence{kind: RecurDaily}
+}
+
+// Returns a rule recurring every week on the given days. If no day is given,
+// the task recurs every seven days.
+func Weekly(days ...time.Weekday) Recurrence {
+	r := Recurrence{kind: RecurWeekly}
+	for _, day := range days {
+		r.weekdays |= 1 << byte(day)
+	}
+	return r
+}
+
+// Returns a rule recurring every month on the given day. If the month has less
+// days, the task recurs on the last day of the month. Returns an error if the
+// day is not between 1 and 31.
+func Monthly(day int) (Recurrence, error) {
+	if day < 1 || day > 31 {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	return Recurrence{kind: RecurMonthly, monthDay: byte(day)}, nil
+}
+
+// Returns a rule recurring every n days. Returns an error if n is not between
+// 1 and 65535.
+func Every(n int) (Recurrence, error) {
+	if n < 1 || n > 65535 {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	return Recurrence{kind: RecurEvery, interval: uint16(n)}, nil
+}
+
+// Returns the kind of the rule: RecurNone, RecurDaily, RecurWeekly,
+// RecurMonthly or RecurEvery.
+func (r Recurrence) Kind() int {
+	return int(r.kind)
+}
+
+// Returns true if the rule is valid, that is if its kind is known and its
+// parameters are in range.
+func (r Recurrence) isValid() bool {
+	switch r.kind {
+	case RecurNone, RecurDaily:
+		return r.weekdays == 0 && r.monthDay == 0 && r.interval == 0
+	case RecurWeekly:
+		return r.weekdays < 1<<7 && r.monthDay == 0 && r.interval == 0
+	case RecurMonthly:
+		return r.monthDay >= 1 && r.monthDay <= 31 && r.weekdays == 0 &&
+			r.interval == 0
+	case RecurEvery:
+		return r.interval >= 1 && r.weekdays == 0 && r.monthDay == 0
+	default:
+		return false
+	}
+}
+
+// Returns the first occurrence strictly after the given time. The time of day
+// of from is kept. For a rule of kind RecurNone, returns from.
+func (r Recurrence) Next(from time.Time) time.Time {
+	switch r.kind {
+	case RecurDaily:
+		return from.AddDate(0, 0, 1)
+	case RecurWeekly:
+		if r.weekdays == 0 {
+			return from.AddDate(0, 0, 7)
+		}
+		for i := 1; i <= 7; i++ {
+			next := from.AddDate(0, 0, i)
+			if r.weekdays&(1<<byte(next.Weekday())) != 0 {
+				return next
+			}
+		}
+	case RecurMonthly:
+		y, m, _ := from.Date()
+		h, mi, sec := from.Clock()
+		for i := 0; ; i++ {
+			// the day 0 of the following month is the last day of the month
+			last := time.Date(y, m+time.Month(i)+1, 0, 0, 0, 0, 0,
+				from.Location()).Day()
+			day := min(int(r.monthDay), last)
+			next := time.Date(y, m+time.Month(i), day, h, mi, sec, 0,
+				from.Location())
+			if next.After(from) {
+				return next
+			}
+		}
+	case RecurEvery:
+		return from.AddDate(0, 0, int(r.interval))
+	}
+	return from
+}
+
+// Returns the string form of the rule, as accepted by ParseRecurrence.
+func (r Recurrence) String() string {
+	switch r.kind {
+	case RecurDaily:
+		return "daily"
+	case RecurWeekly:
+		if r.weekdays == 0 {
+			return "weekly"
+		}
+		var days []string
+		// weeks are written starting on monday
+		for i := 1; i <= 7; i++ {
+			if r.weekdays&(1<<byte(i%7)) != 0 {
+				days = append(days, weekdayNames[i%7])
+			}
+		}
+		return "weekly:" + strings.Join(days, ",")
+	case RecurMonthly:
+		return "monthly:" + strconv.Itoa(int(r.monthDay))
+	case RecurEvery:
+		return "every:" + strconv.Itoa(int(r.interval))
+	default:
+		return "none"
+	}
+}
+
+// Parses the recurrence rule denoted by the given string. Accepted forms are
+// "none", "daily", "weekly", "weekly:mon,thu" (every week on the given days),
+// "monthly:15" (every month on the given day) and "every:3" (every 3 days).
+func ParseRecurrence(rule string) (Recurrence, error) {
+	kind, arg, hasArg := strings.Cut(rule, ":")
+	switch kind {
+	case "none":
+		if !hasArg {
+			return Recurrence{}, nil
+		}
+	case "daily":
+		if !hasArg {
+			return Daily(), nil
+		}
+	case "weekly":
+		if !hasArg {
+			return Weekly(), nil
+		}
+		var days []time.Weekday
+		for _, name := range strings.Split(arg, ",") {
+			day := slices.Index(weekdayNames, name)
+			if day < 0 {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			days = append(days, time.Weekday(day))
+		}
+		return Weekly(days...), nil
+	case "monthly":
+		day, err := strconv.Atoi(arg)
+		if err != nil {
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+		return Monthly(day)
+	case "every":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+		return Every(n)
+	}
+	return Recurrence{}, ErrInvalidRecurrence
+}
@@ -0,0 +1,80 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrenceAndStringAreInverse(t *testing.T) {
+	rules := []string{"none", "daily", "weekly", "weekly:mon,fri",
+		"weekly:sat,sun", "monthly:31", "every:3"}
+	for _, str := range rules {
+		rule, err := ParseRecurrence(str)
+		if err != nil {
+			t.Fatalf("%s: %s", str, err.Error())
+		}
+		if rule.String() != str {
+			t.Fatalf("got \"%s\", want \"%s\"", rule.String(), str)
+		}
+	}
+}
+
+func TestParseInvalidRecurrenceReturnsError(t *testing.T) {
+	rules := []string{"", "yearly", "daily:2", "weekly:mon,funday",
+		"monthly", "monthly:0", "monthly:32", "every:0", "every:x"}
+	for _, str := range rules {
+		if _, err := ParseRecurrence(str); err != ErrInvalidRecurrence {
+			t.Fatalf("%s: got %v, want %v", str, err, ErrInvalidRecurrence)
+		}
+	}
+}
+
+func TestNextOfDailyRuleIsTheNextDay(t *testing.T) {
+	from := time.Date(2026, time.October, 31, 8, 30, 0, 0, time.UTC)
+	want := time.Date(2026, time.November, 1, 8, 30, 0, 0, time.UTC)
+	if got := Daily().Next(from); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestNextOfWeeklyRuleIsTheNextGivenWeekDay(t *testing.T) {
+	rule := Weekly(time.Monday, time.Thursday)
+	// a friday
+	from := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	want := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	if got := rule.Next(from); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	want = time.Date(2026, time.October, 22, 0, 0, 0, 0, time.UTC)
+	if got := rule.Next(rule.Next(from)); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestNextOfMonthlyRuleIsClampedToTheLastDayOfTheMonth(t *testing.T) {
+	rule, err := Monthly(31)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	from := time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)
+	want := time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC)
+	if got := rule.Next(from); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	want = time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	if got := rule.Next(rule.Next(from)); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestNextOfEveryRuleAddsTheInterval(t *testing.T) {
+	rule, err := Every(10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	from := time.Date(2026, time.October, 25, 0, 0, 0, 0, time.UTC)
+	want := time.Date(2026, time.November, 4, 0, 0, 0, 0, time.UTC)
+	if got := rule.Next(from); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
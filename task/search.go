@@ -0,0 +1,114 @@
+package task
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var ErrNoSearchTerm = errors.New("no search term given")
+
+// A SearchResult is a task matching search terms.
+type SearchResult struct {
+	Task         *Task    // the matching task
+	TitleHits    int      // the number of matches of the terms in the title
+	DescHits     int      // the number of matches of the terms in the description
+	TitleMatches [][2]int // the sorted byte ranges of the matches in the title
+	DescMatches  [][2]int // the sorted byte ranges of the matches in the description
+}
+
+// Returns the tasks whose title or description match every given term,
+// case-insensitively, with their matches, the most relevant first. Terms are
+// regular expressions if regex is true, and plain text otherwise. The tasks
+// with the most matches in their title come first, then those with the most
+// matches in their description. Tasks with as many matches keep their order.
+func Search(tasks []*Task, terms []string, regex bool) ([]SearchResult, error) {
+	if len(terms) == 0 {
+		return nil, ErrNoSearchTerm
+	}
+	var exprs []*regexp.Regexp
+	for _, term := range terms {
+		if !regex {
+			term = regexp.QuoteMeta(term)
+		}
+		// compiled alone first for errors to quote the term as given
+		if _, err := regexp.Compile(term); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, regexp.MustCompile("(?i)"+term))
+	}
+	var results []SearchResult
+	for _, t := range tasks {
+		res := SearchResult{Task: t}
+		matched := true
+		for _, expr := range exprs {
+			inTitle := findMatches(expr, t.title)
+			inDesc := findMatches(expr, t.desc)
+			if len(inTitle) == 0 && len(inDesc) == 0 {
+				matched = false
+				break
+			}
+			res.TitleHits += len(inTitle)
+			res.DescHits += len(inDesc)
+			res.TitleMatches = append(res.TitleMatches, inTitle...)
+			res.DescMatches = append(res.DescMatches, inDesc...)
+		}
+		if matched {
+			res.TitleMatches = mergeRanges(res.TitleMatches)
+			res.DescMatches = mergeRanges(res.DescMatches)
+			results = append(results, res)
+		}
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		if a.TitleHits != b.TitleHits {
+			return b.TitleHits - a.TitleHits
+		}
+		return b.DescHits - a.DescHits
+	})
+	return results, nil
+}
+
+// Returns the byte ranges of the non-empty matches of the given expression in
+// the given text.
+func findMatches(expr *regexp.Regexp, text string) [][2]int {
+	var res [][2]int
+	for _, loc := range expr.FindAllStringIndex(text, -1) {
+		if loc[0] != loc[1] {
+			res = append(res, [2]int{loc[0], loc[1]})
+		}
+	}
+	return res
+}
+
+// Returns the given ranges sorted, the overlapping ones being merged.
+func mergeRanges(ranges [][2]int) [][2]int {
+	slices.SortFunc(ranges, func(a, b [2]int) int {
+		return a[0] - b[0]
+	})
+	var res [][2]int
+	for _, r := range ranges {
+		if len(res) != 0 && r[0] <= res[len(res)-1][1] {
+			res[len(res)-1][1] = max(res[len(res)-1][1], r[1])
+			continue
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+// Returns the given text with every given sorted byte range surrounded by open
+// and close.
+func Highlight(text string, ranges [][2]int, open, close string) string {
+	var sb strings.Builder
+	last := 0
+	for _, r := range ranges {
+		sb.WriteString(text[last:r[0]])
+		sb.WriteString(open)
+		sb.WriteString(text[r[0]:r[1]])
+		sb.WriteString(close)
+		last = r[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
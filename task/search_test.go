@@ -0,0 +1,93 @@
+package task
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearchRanksTitleMatchesFirst(t *testing.T) {
+	inDesc, _ := NewTask("write docs", "explain how to deploy, deploy again",
+		false, Medium, Todo)
+	inTitle, _ := NewTask("Deploy api", "", false, Medium, Todo)
+	none, _ := NewTask("release", "nothing here", false, Medium, Todo)
+	results, err := Search([]*Task{inDesc, none, inTitle}, []string{"DEPLOY"},
+		false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(results) != 2 || results[0].Task != inTitle ||
+		results[1].Task != inDesc {
+		t.Fatalf("got %d results, want the title match first", len(results))
+	}
+	if results[0].TitleHits != 1 || results[1].DescHits != 2 {
+		t.Fatalf("got %d title hits and %d description hits",
+			results[0].TitleHits, results[1].DescHits)
+	}
+	want := [][2]int{{15, 21}, {23, 29}}
+	if !slices.Equal(results[1].DescMatches, want) {
+		t.Fatalf("got %v, want %v", results[1].DescMatches, want)
+	}
+}
+
+func TestSearchRanksTitleMatchAboveManyDescriptionMatches(t *testing.T) {
+	inDesc, _ := NewTask("write docs", strings.Repeat("deploy ", 150), false,
+		Medium, Todo)
+	inTitle, _ := NewTask("Deploy api", "", false, Medium, Todo)
+	results, err := Search([]*Task{inDesc, inTitle}, []string{"deploy"}, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(results) != 2 || results[0].Task != inTitle {
+		t.Fatalf("got %d results, want the title match first", len(results))
+	}
+	if results[1].DescHits != 150 {
+		t.Fatalf("got %v, want %v", results[1].DescHits, 150)
+	}
+}
+
+func TestSearchRequiresEveryTerm(t *testing.T) {
+	both, _ := NewTask("deploy api", "on friday", false, Medium, Todo)
+	one, _ := NewTask("deploy web", "", false, Medium, Todo)
+	results, err := Search([]*Task{both, one}, []string{"deploy", "friday"},
+		false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(results) != 1 || results[0].Task != both {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+}
+
+func TestSearchRegexAndPlainModes(t *testing.T) {
+	ts, _ := NewTask("fix bug 1234", "a.b", false, Medium, Todo)
+	results, err := Search([]*Task{ts}, []string{`bug \d+`}, true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(results) != 1 || !slices.Equal(results[0].TitleMatches,
+		[][2]int{{4, 12}}) {
+		t.Fatalf("got %v, want one match of bug 1234", results)
+	}
+	results, err = Search([]*Task{ts}, []string{`x.b`}, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(results) != 0 {
+		t.Fatalf("got %d results, want none as . is not a pattern", len(results))
+	}
+	if _, err = Search([]*Task{ts}, []string{"("}, true); err == nil {
+		t.Fatalf("expected error for invalid regular expression")
+	}
+	if _, err = Search([]*Task{ts}, nil, false); err != ErrNoSearchTerm {
+		t.Fatalf("got %v, want %v", err, ErrNoSearchTerm)
+	}
+}
+
+func TestHighlightMergesOverlappingMatches(t *testing.T) {
+	ranges := mergeRanges([][2]int{{4, 8}, {0, 2}, {6, 10}})
+	got := Highlight("deploy the api", ranges, "[", "]")
+	if got != "[de]pl[oy the] api" {
+		t.Fatalf("got %q", got)
+	}
+}
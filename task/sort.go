@@ -0,0 +1,154 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// The sort keys of the tasks listed without explicit order: the priority from
+// high to low, then the creation time from oldest to newest.
+const DefaultSort = "priority:desc,created"
+
+var (
+	ErrInvalidSortKey = errors.New("sort key must be priority, status, created, modified, started, completed, due or title, optionally followed by :asc or :desc")
+	ErrInvalidGroupBy = errors.New("tasks can be grouped by status, priority, project or tag")
+)
+
+// A SortKey is a field tasks are sorted by, in ascending or descending order.
+type SortKey struct {
+	Field string // "priority", "status", "title" or a time field of timeOf
+	Desc  bool   // true for the descending order
+}
+
+// Parses the given comma separated sort keys, each being a field optionally
+// followed by ":asc" (the default) or ":desc", such as "priority:desc,due".
+func ParseSortKeys(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(spec, ",") {
+		field, order, hasOrder := strings.Cut(part, ":")
+		switch field {
+		case "priority", "status", "created", "modified", "started",
+			"completed", "due", "title":
+		default:
+			return nil, ErrInvalidSortKey
+		}
+		if hasOrder && order != "asc" && order != "desc" {
+			return nil, ErrInvalidSortKey
+		}
+		keys = append(keys, SortKey{field, order == "desc"})
+	}
+	return keys, nil
+}
+
+// Returns the comparison of the given tasks on the given field, as returned by
+// strings.Compare, and true if it must not be reversed in descending order:
+// tasks missing the time of a time field come after the others in both
+// orders.
+func compareField(a, b *Task, field string) (int, bool) {
+	switch field {
+	case "priority":
+		return int(a.priority) - int(b.priority), false
+	case "status":
+		return int(a.status) - int(b.status), false
+	case "created", "modified", "started", "completed", "due":
+		at, bt := a.timeOf(field), b.timeOf(field)
+		if at.IsZero() != bt.IsZero() {
+			return missingLast(at.IsZero()), true
+		}
+		return at.Compare(bt), false
+	default:
+		return strings.Compare(strings.ToLower(a.title),
+			strings.ToLower(b.title)), false
+	}
+}
+
+// Returns the comparison putting a task missing a field after one having it.
+func missingLast(aMissing bool) int {
+	if aMissing {
+		return 1
+	}
+	return -1
+}
+
+// Sorts the given tasks by the given keys, the first key being the most
+// significant. Tasks equal on every key are sorted by uuid.
+func SortTasks(tasks []*Task, keys []SortKey) {
+	slices.SortStableFunc(tasks, func(a, b *Task) int {
+		for _, key := range keys {
+			c, fixed := compareField(a, b, key.Field)
+			if key.Desc && !fixed {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(a.uuid, b.uuid)
+	})
+}
+
+// A TaskGroup is a named group of tasks.
+type TaskGroup struct {
+	Name  string
+	Tasks []*Task
+}
+
+// Groups the given tasks by the given field: "status", "priority", "project"
+// or "tag". The groups of status and priority are in the order of the values,
+// the highest priority first, and the other groups are sorted by name, the
+// tasks without project or tag coming last. A task with several tags is part
+// of the group of each of its tags. Tasks keep their order within groups.
+func GroupTasks(tasks []*Task, field string) ([]TaskGroup, error) {
+	var names func(t *Task) []string
+	var order []string
+	switch field {
+	case "status":
+		order = []string{"todo", "doing", "done"}
+		names = func(t *Task) []string {
+			return []string{StatusName(t.Status())}
+		}
+	case "priority":
+		order = []string{"high", "medium", "low"}
+		names = func(t *Task) []string {
+			return []string{PriorityName(t.Priority())}
+		}
+	case "project":
+		names = func(t *Task) []string {
+			return []string{t.project}
+		}
+	case "tag":
+		names = func(t *Task) []string {
+			if len(t.tags) == 0 {
+				return []string{""}
+			}
+			return t.tags
+		}
+	default:
+		return nil, ErrInvalidGroupBy
+	}
+	byName := make(map[string][]*Task)
+	for _, t := range tasks {
+		for _, name := range names(t) {
+			byName[name] = append(byName[name], t)
+		}
+	}
+	if order == nil {
+		for name := range byName {
+			order = append(order, name)
+		}
+		slices.SortFunc(order, func(a, b string) int {
+			if (a == "") != (b == "") {
+				return missingLast(a == "")
+			}
+			return strings.Compare(a, b)
+		})
+	}
+	var groups []TaskGroup
+	for _, name := range order {
+		if len(byName[name]) != 0 {
+			groups = append(groups, TaskGroup{name, byName[name]})
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,99 @@
+package task
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSortKeys(t *testing.T) {
+	keys, err := ParseSortKeys("priority:desc,due,title:asc")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := []SortKey{{"priority", true}, {"due", false}, {"title", false}}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+	for _, spec := range []string{"", "size", "due:up", "priority,"} {
+		if _, err = ParseSortKeys(spec); err != ErrInvalidSortKey {
+			t.Fatalf("%q: got %v, want %v", spec, err, ErrInvalidSortKey)
+		}
+	}
+}
+
+func TestSortTasksByDefaultKeys(t *testing.T) {
+	old, _ := NewTask("old", "", false, Medium, Todo)
+	old.created = time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+	recent, _ := NewTask("recent", "", false, Medium, Todo)
+	recent.created = time.Date(2026, time.June, 1, 0, 0, 0, 0, time.Local)
+	unknown, _ := NewTask("unknown", "", false, Medium, Todo)
+	unknown.created = time.Time{}
+	urgent, _ := NewTask("urgent", "", false, High, Todo)
+	tasks := []*Task{unknown, recent, old, urgent}
+	keys, _ := ParseSortKeys(DefaultSort)
+	SortTasks(tasks, keys)
+	want := []*Task{urgent, old, recent, unknown}
+	if !slices.Equal(tasks, want) {
+		t.Fatalf("got %v, want %v", sortedTitles(tasks), sortedTitles(want))
+	}
+}
+
+func TestSortTasksByDueKeepsTasksWithoutDueLast(t *testing.T) {
+	early, _ := NewDefault("early")
+	early.SetDue(time.Date(2026, time.October, 1, 0, 0, 0, 0, time.Local))
+	late, _ := NewDefault("late")
+	late.SetDue(time.Date(2026, time.December, 1, 0, 0, 0, 0, time.Local))
+	none, _ := NewDefault("none")
+	tasks := []*Task{none, late, early}
+	SortTasks(tasks, []SortKey{{"due", true}})
+	if !slices.Equal(tasks, []*Task{late, early, none}) {
+		t.Fatalf("got %s, %s, %s", tasks[0].Title(), tasks[1].Title(),
+			tasks[2].Title())
+	}
+	SortTasks(tasks, []SortKey{{"due", false}})
+	if !slices.Equal(tasks, []*Task{early, late, none}) {
+		t.Fatalf("got %s, %s, %s", tasks[0].Title(), tasks[1].Title(),
+			tasks[2].Title())
+	}
+}
+
+func TestGroupTasks(t *testing.T) {
+	a, _ := NewTask("a", "", false, Low, Done)
+	a.AddTag("x")
+	a.AddTag("y")
+	a.SetProject("infra")
+	b, _ := NewTask("b", "", false, High, Todo)
+	b.AddTag("x")
+	c, _ := NewTask("c", "", false, High, Done)
+	tasks := []*Task{a, b, c}
+	tests := []struct {
+		field string
+		names []string
+		sizes []int
+	}{
+		{"status", []string{"todo", "done"}, []int{1, 2}},
+		{"priority", []string{"high", "low"}, []int{2, 1}},
+		{"project", []string{"infra", ""}, []int{1, 2}},
+		{"tag", []string{"x", "y", ""}, []int{2, 1, 1}},
+	}
+	for _, test := range tests {
+		groups, err := GroupTasks(tasks, test.field)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		var names []string
+		var sizes []int
+		for _, group := range groups {
+			names = append(names, group.Name)
+			sizes = append(sizes, len(group.Tasks))
+		}
+		if !slices.Equal(names, test.names) || !slices.Equal(sizes, test.sizes) {
+			t.Fatalf("%s: got %v %v, want %v %v", test.field, names, sizes,
+				test.names, test.sizes)
+		}
+	}
+	if _, err := GroupTasks(tasks, "due"); err != ErrInvalidGroupBy {
+		t.Fatalf("got %v, want %v", err, ErrInvalidGroupBy)
+	}
+}
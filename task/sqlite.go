@@ -0,0 +1,262 @@
+package task
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// The schema of the database of a SQLiteStore. Tasks are stored encoded in the
+// current file format, the status, the priority and the due date being copied
+// in indexed columns to filter tasks without decoding them.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	uuid     TEXT PRIMARY KEY,
+	status   INTEGER NOT NULL,
+	priority INTEGER NOT NULL,
+	due      INTEGER,
+	data     BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tasks_status ON tasks (status);
+CREATE INDEX IF NOT EXISTS tasks_priority ON tasks (priority);
+CREATE INDEX IF NOT EXISTS tasks_due ON tasks (due);
+`
+
+// The statement methods shared by a database and a transaction.
+type sqlRunner interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// A SQLiteStore keeps every task in a single SQLite database file.
+type SQLiteStore struct {
+	db  *sql.DB   // the database
+	run sqlRunner // the database, or the transaction of a batch
+}
+
+// Opens the SQLite store of given database file path, creating the database
+// if it does not exist.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if path == "" {
+		return nil, ErrInvalidLoadPath
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db, run: db}, nil
+}
+
+// Closes the database of the store.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Returns the tasks of the rows returned by the given query, rewriting in the
+// current format version the tasks stored in an older one.
+func (s *SQLiteStore) query(query string, args ...any) ([]*Task, error) {
+	rows, err := s.run.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tasks []*Task
+	var outdated []*Task
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		ts, version, err := decodeTask(data)
+		if err != nil {
+			return nil, err
+		}
+		if version < FormatVersion {
+			outdated = append(outdated, ts)
+		}
+		tasks = append(tasks, ts)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for _, ts := range outdated {
+		// the task is usable even if the upgrade fails, it is then retried
+		// on the next load
+		s.Save(ts)
+	}
+	return tasks, nil
+}
+
+func (s *SQLiteStore) LoadAll() ([]*Task, error) {
+	return s.query("SELECT data FROM tasks ORDER BY uuid")
+}
+
+// Returns the smallest string greater than every string having the given
+// prefix, so that uuid prefix lookups are range queries using the primary key
+// index. Uuids only hold ASCII characters.
+func prefixUpperBound(prefix string) string {
+	return prefix + "\x7f"
+}
+
+func (s *SQLiteStore) Load(prefix string) ([]*Task, error) {
+	if prefix == "" {
+		return nil, ErrInvalidUuid
+	}
+	return s.query("SELECT data FROM tasks WHERE uuid >= ? AND uuid < ? "+
+		"ORDER BY uuid", prefix, prefixUpperBound(prefix))
+}
+
+func (s *SQLiteStore) Save(t *Task) error {
+	var due any = nil
+	if t.HasDue() {
+		due = t.due.Unix()
+	}
+	_, err := s.run.Exec("INSERT OR REPLACE INTO tasks "+
+		"(uuid, status, priority, due, data) VALUES (?, ?, ?, ?, ?)",
+		t.uuid, t.status, t.priority, due, t.encode())
+	return err
+}
+
+func (s *SQLiteStore) Remove(uuid string) error {
+	_, err := s.run.Exec("DELETE FROM tasks WHERE uuid = ?", uuid)
+	return err
+}
+
+func (s *SQLiteStore) Exists(prefix string) (bool, error) {
+	if prefix == "" {
+		return false, ErrInvalidUuid
+	}
+	rows, err := s.run.Query("SELECT 1 FROM tasks WHERE uuid >= ? AND "+
+		"uuid < ? LIMIT 1", prefix, prefixUpperBound(prefix))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+// Calls fn with a store whose changes are made in a transaction, committed if
+// fn returns nil and rolled back otherwise.
+func (s *SQLiteStore) Batch(fn func(Store) error) error {
+	if _, ok := s.run.(*sql.Tx); ok {
+		return fn(s)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(&SQLiteStore{db: s.db, run: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Returns the tasks matching the given filters of FilterTasks at the given
+// time. The status, priority and due filters are applied by indexed queries,
+// unless dependency filters are given as they need every task.
+func (s *SQLiteStore) LoadFiltered(filters []string, now time.Time) ([]*Task,
+	error) {
+	if len(ParseDependencyFilterFrom(filters)) != 0 {
+		tasks, err := s.LoadAll()
+		if err != nil {
+			return nil, err
+		}
+		return filterTasksAt(tasks, filters, now)
+	}
+	query := "SELECT data FROM tasks WHERE 1"
+	var args []any
+	sFilters, err := ParseStatusFrom(filters)
+	if err != nil {
+		return nil, err
+	}
+	pFilters, err := ParsePriorityFrom(filters)
+	if err != nil {
+		return nil, err
+	}
+	if len(sFilters) != 0 {
+		query += " AND status IN (?" +
+			strings.Repeat(", ?", len(sFilters)-1) + ")"
+		for _, status := range sFilters {
+			args = append(args, status)
+		}
+	}
+	if len(pFilters) != 0 {
+		query += " AND priority IN (?" +
+			strings.Repeat(", ?", len(pFilters)-1) + ")"
+		for _, prio := range pFilters {
+			args = append(args, prio)
+		}
+	}
+	// the due filters select a superset of the matching tasks, refined by
+	// filterTasksAt
+	today := startOfDay(now)
+	var dueRanges []string
+	for _, filter := range ParseDueFilterFrom(filters) {
+		switch filter {
+		case "overdue":
+			dueRanges = append(dueRanges, "(due < ? AND status != ?)")
+			args = append(args, now.Unix(), Done)
+		case "today":
+			dueRanges = append(dueRanges, "(due >= ? AND due < ?)")
+			args = append(args, today.Unix(), today.AddDate(0, 0, 1).Unix())
+		case "week":
+			dueRanges = append(dueRanges, "(due >= ? AND due < ?)")
+			args = append(args, today.Unix(), today.AddDate(0, 0, 7).Unix())
+		}
+	}
+	if len(dueRanges) != 0 {
+		query += " AND (" + strings.Join(dueRanges, " OR ") + ")"
+	}
+	tasks, err := s.query(query+" ORDER BY uuid", args...)
+	if err != nil {
+		return nil, err
+	}
+	return filterTasksAt(tasks, filters, now)
+}
+
+// Rewrites in the current format version every task of the store that is
+// stored in an older version. Returns the number of rewritten tasks and the
+// errors of the tasks that could not be converted.
+func (s *SQLiteStore) Migrate() (int, []*MigrationError, error) {
+	rows, err := s.run.Query("SELECT uuid, data FROM tasks ORDER BY uuid")
+	if err != nil {
+		return 0, nil, err
+	}
+	var outdated []*Task
+	var failures []*MigrationError
+	for rows.Next() {
+		var uuid string
+		var data []byte
+		if err = rows.Scan(&uuid, &data); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		ts, version, err := decodeTask(data)
+		if err != nil {
+			failures = append(failures, &MigrationError{uuid, err})
+		} else if version < FormatVersion {
+			outdated = append(outdated, ts)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	migrated := 0
+	for _, ts := range outdated {
+		if err = s.Save(ts); err != nil {
+			failures = append(failures, &MigrationError{ts.uuid, err})
+			continue
+		}
+		migrated++
+	}
+	return migrated, failures, nil
+}
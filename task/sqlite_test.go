@@ -0,0 +1,174 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Opens a SQLite store in a temporary directory removed at the end of the
+// test.
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	store, err := OpenSQLiteStore(filepath.Join(dirname, "tasks.db"))
+	if err != nil {
+		os.RemoveAll(dirname)
+		t.Fatalf(err.Error())
+	}
+	t.Cleanup(func() {
+		store.Close()
+		os.RemoveAll(dirname)
+	})
+	return store
+}
+
+func TestSQLiteStoreLoadsSavesAndRemovesTasks(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ts, err := NewTask("test", "a description", true, High, Doing)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetDue(time.Date(2026, time.November, 1, 0, 0, 0, 0, time.Local))
+	if err = store.Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetStatus(Done)
+	if err = store.Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err := store.Load(ts.Uuid()[:5])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("got %d tasks, want 1", len(tasks))
+	}
+	if tasks[0].Status() != Done || !tasks[0].Due().Equal(ts.Due()) {
+		t.Fatalf("expected the last saved task")
+	}
+	exists, err := store.Exists(ts.Uuid()[:5])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !exists {
+		t.Fatalf("got false, want true")
+	}
+	if err = store.Remove(ts.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err = store.LoadAll()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 0 {
+		t.Fatalf("got %d tasks, want 0", len(tasks))
+	}
+}
+
+func TestSQLiteStoreLoadFilteredMatchesFilterTasks(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	now := time.Date(2026, time.October, 17, 10, 0, 0, 0, time.Local)
+	var tasks []*Task
+	for _, prio := range []byte{Low, Medium, High} {
+		for _, status := range []byte{Todo, Doing, Done} {
+			for _, due := range []time.Time{{}, now.Add(-time.Hour),
+				now.AddDate(0, 0, 1), now.AddDate(0, 0, 10)} {
+				ts, _ := NewTask("test", "", false, prio, status)
+				ts.SetDue(due)
+				if err := store.Save(ts); err != nil {
+					t.Fatalf(err.Error())
+				}
+				tasks = append(tasks, ts)
+			}
+		}
+	}
+	slices.SortFunc(tasks, func(a, b *Task) int {
+		return strings.Compare(a.Uuid(), b.Uuid())
+	})
+	filterSets := [][]string{{}, {"done"}, {"todo", "high"},
+		{"overdue"}, {"today", "week", "low"}, {"week", "doing", "medium"}}
+	for _, filters := range filterSets {
+		want, err := filterTasksAt(tasks, filters, now)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		got, err := store.LoadFiltered(filters, now)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%v: got %d tasks, want %d", filters, len(got),
+				len(want))
+		}
+		for i := range got {
+			if got[i].Uuid() != want[i].Uuid() {
+				t.Fatalf("%v: got %s, want %s", filters, got[i].Uuid(),
+					want[i].Uuid())
+			}
+		}
+	}
+}
+
+func TestSQLiteStoreBatchIsRolledBackOnError(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ts1, _ := NewDefault("test1")
+	ts2, _ := NewDefault("test2")
+	failure := errors.New("failure")
+	err := Batch(store, func(s Store) error {
+		if err := s.Save(ts1); err != nil {
+			return err
+		}
+		if err := s.Save(ts2); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+	tasks, err := store.LoadAll()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 0 {
+		t.Fatalf("got %d tasks, want 0", len(tasks))
+	}
+}
+
+func TestCopyTasksFromFileStoreToSQLiteStoreAndBack(t *testing.T) {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	files := NewFileStore(dirname)
+	for _, title := range []string{"a", "b", "c"} {
+		ts, _ := NewDefault(title)
+		ts.SetRecurrence(Weekly(time.Monday))
+		if err = files.Save(ts); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	db := newTestSQLiteStore(t)
+	n, err := CopyTasks(files, db)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if n != 3 {
+		t.Fatalf("got %d copied tasks, want 3", n)
+	}
+	back := NewMemoryStore()
+	if n, err = CopyTasks(db, back); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if n != 3 {
+		t.Fatalf("got %d copied tasks, want 3", n)
+	}
+}
@@ -0,0 +1,287 @@
+package task
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A Store keeps tasks, addressed by their uuid.
+type Store interface {
+	// Returns every task of the store.
+	LoadAll() ([]*Task, error)
+	// Returns the tasks whose uuid has the given prefix.
+	Load(prefix string) ([]*Task, error)
+	// Saves the given task, replacing the task of same uuid if any.
+	Save(t *Task) error
+	// Removes the task of given full uuid. Removing a task that does not exist
+	// is not an error.
+	Remove(uuid string) error
+	// Returns true if a task whose uuid has the given prefix exists.
+	Exists(prefix string) (bool, error)
+}
+
+// A Batcher is a Store able to apply several changes at once: either every
+// change is applied or none is.
+type Batcher interface {
+	// Calls fn with a store whose changes are applied if fn returns nil and
+	// discarded otherwise.
+	Batch(fn func(Store) error) error
+}
+
+// A FilterStore is a Store able to load the tasks matching filters of
+// FilterTasks without loading every task.
+type FilterStore interface {
+	// Returns the tasks matching the given filters at the given time, as
+	// FilterTasks would.
+	LoadFiltered(filters []string, now time.Time) ([]*Task, error)
+}
+
+// A Migrator is a Store able to rewrite its tasks stored in an older format
+// version in the current one.
+type Migrator interface {
+	// Returns the number of rewritten tasks and the errors of the tasks that
+	// could not be converted.
+	Migrate() (int, []*MigrationError, error)
+}
+
+// Calls fn with a store applying its changes at once if the given store is a
+// Batcher, with the given store itself otherwise.
+func Batch(store Store, fn func(Store) error) error {
+	if b, ok := store.(Batcher); ok {
+		return b.Batch(fn)
+	}
+	return fn(store)
+}
+
+// Returns the tasks of the given store matching the given filters of
+// FilterTasks.
+func LoadFiltered(store Store, filters []string) ([]*Task, error) {
+	return loadFilteredAt(store, filters, time.Now())
+}
+
+// Returns the tasks of the given store matching the given filters of
+// FilterTasks at the given time.
+func loadFilteredAt(store Store, filters []string, now time.Time) ([]*Task,
+	error) {
+	if fs, ok := store.(FilterStore); ok {
+		return fs.LoadFiltered(filters, now)
+	}
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	return filterTasksAt(tasks, filters, now)
+}
+
+// Closes the given store if it is an io.Closer.
+func closeStore(store Store) error {
+	if closer, ok := store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Returns the only task of the given store whose uuid has the given prefix.
+// Returns ErrTaskNotFound if there is no such task and ErrUuidNotUnique if
+// there are several.
+func LoadUnique(store Store, prefix string) (*Task, error) {
+	if len(prefix) == 0 || len(prefix) > 36 {
+		return nil, ErrInvalidUuid
+	}
+	tasks, err := store.Load(prefix)
+	if err != nil {
+		return nil, err
+	}
+	switch len(tasks) {
+	case 0:
+		return nil, ErrTaskNotFound
+	case 1:
+		return tasks[0], nil
+	default:
+		return nil, ErrUuidNotUnique
+	}
+}
+
+// Copies every task of the store from to the store to, in a single batch if
+// to is a Batcher, and checks that every task can be loaded back unchanged
+// from to. Tasks of to that are not in from are kept. Returns the number of
+// copied tasks.
+func CopyTasks(from, to Store) (int, error) {
+	tasks, err := from.LoadAll()
+	if err != nil {
+		return 0, err
+	}
+	err = Batch(to, func(s Store) error {
+		for _, ts := range tasks {
+			if err := s.Save(ts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, ts := range tasks {
+		copied, err := to.Load(ts.uuid)
+		if err != nil {
+			return 0, err
+		}
+		if len(copied) != 1 || !bytes.Equal(copied[0].encode(), ts.encode()) {
+			return 0, fmt.Errorf("task %s was not copied unchanged", ts.uuid)
+		}
+	}
+	return len(tasks), nil
+}
+
+// The store used by the package level functions, such as LoadTasks or Remove,
+// and by SaveOnDisk. If it is nil, a FileStore at TasksPath is used.
+var DefaultStore Store = nil
+
+// Returns DefaultStore if it is set, a FileStore at TasksPath otherwise.
+func defaultStore() Store {
+	if DefaultStore != nil {
+		return DefaultStore
+	}
+	return NewFileStore(TasksPath)
+}
+
+// A FileStore keeps every task in its own file, named after the uuid of the
+// task, in a directory.
+type FileStore struct {
+	path string // the path of the tasks directory
+}
+
+// Returns a store keeping its tasks in the directory of given path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Returns the path of the tasks directory of the store.
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+func (s *FileStore) LoadAll() ([]*Task, error) {
+	return loadTasksFrom(s.path)
+}
+
+func (s *FileStore) Load(prefix string) ([]*Task, error) {
+	if prefix == "" || s.path == "" {
+		return nil, ErrInvalidLoadPath
+	}
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*Task
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || entry.IsDir() ||
+			isTempFile(name) {
+			continue
+		}
+		ts, err := loadTaskFrom(filepath.Join(s.path, name))
+		if err != nil && isCorruptionError(err) {
+			if qErr := quarantine(s.path, name, err); qErr != nil {
+				logf("skipped %s: %s", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, ts)
+	}
+	return tasks, nil
+}
+
+func (s *FileStore) Save(t *Task) error {
+	return t.saveAt(s.path)
+}
+
+func (s *FileStore) Remove(uuid string) error {
+	if uuid == "" || s.path == "" {
+		return ErrInvalidLoadPath
+	}
+	err := os.Remove(filepath.Join(s.path, uuid))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) Exists(prefix string) (bool, error) {
+	return existsAt(s.path, prefix)
+}
+
+// A MemoryStore keeps its tasks in memory. The tasks are copied on save and on
+// load, so that modifying a task does not modify the store.
+type MemoryStore struct {
+	tasks map[string][]byte // the encoded tasks by uuid
+}
+
+// Returns an empty memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tasks: make(map[string][]byte)}
+}
+
+// Returns the sorted uuids of the tasks of the store having the given prefix.
+func (s *MemoryStore) uuids(prefix string) []string {
+	var res []string
+	for uuid := range s.tasks {
+		if strings.HasPrefix(uuid, prefix) {
+			res = append(res, uuid)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Returns the decoded tasks of given uuids.
+func (s *MemoryStore) decode(uuids []string) ([]*Task, error) {
+	var tasks []*Task
+	for _, uuid := range uuids {
+		ts, _, err := decodeTask(s.tasks[uuid])
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, ts)
+	}
+	return tasks, nil
+}
+
+func (s *MemoryStore) LoadAll() ([]*Task, error) {
+	return s.decode(s.uuids(""))
+}
+
+func (s *MemoryStore) Load(prefix string) ([]*Task, error) {
+	if prefix == "" {
+		return nil, ErrInvalidUuid
+	}
+	return s.decode(s.uuids(prefix))
+}
+
+func (s *MemoryStore) Save(t *Task) error {
+	s.tasks[t.uuid] = t.encode()
+	return nil
+}
+
+func (s *MemoryStore) Remove(uuid string) error {
+	delete(s.tasks, uuid)
+	return nil
+}
+
+func (s *MemoryStore) Exists(prefix string) (bool, error) {
+	if prefix == "" {
+		return false, ErrInvalidUuid
+	}
+	return len(s.uuids(prefix)) > 0, nil
+}
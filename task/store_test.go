@@ -0,0 +1,149 @@
+package task
+
+import (
+	"os"
+	"testing"
+)
+
+// Sets DefaultStore to a new memory store holding the given tasks for the
+// duration of the test and returns it.
+func useMemoryStore(t *testing.T, tasks ...*Task) *MemoryStore {
+	store := NewMemoryStore()
+	for _, ts := range tasks {
+		if err := store.Save(ts); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	DefaultStore = store
+	t.Cleanup(func() { DefaultStore = nil })
+	return store
+}
+
+func TestSaveOnDiskSavesInDefaultStore(t *testing.T) {
+	store := useMemoryStore(t)
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = ts.SaveOnDisk(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	exists, err := store.Exists(ts.Uuid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !exists {
+		t.Fatalf("expected task in store")
+	}
+}
+
+func TestModifyingLoadedTaskDoesNotModifyMemoryStore(t *testing.T) {
+	ts, _ := NewDefault("test")
+	useMemoryStore(t, ts)
+	loaded, err := LoadTask(ts.Uuid()[:4])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	loaded.SetStatus(Done)
+	reloaded, err := LoadTask(ts.Uuid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if reloaded.Status() != Todo {
+		t.Fatalf("got %d, want %d", reloaded.Status(), Todo)
+	}
+}
+
+func TestExistsAndIsUniqueWithSharedPrefixReturnsFalse(t *testing.T) {
+	ts1, _ := NewDefault("test1")
+	ts2, _ := NewDefault("test2")
+	ts2.uuid = ts1.uuid[:8] + ts2.uuid[8:]
+	useMemoryStore(t, ts1, ts2)
+	unique, err := ExistsAndIsUnique(ts1.Uuid()[:8])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if unique {
+		t.Fatalf("got true, want false")
+	}
+	unique, err = ExistsAndIsUnique(ts1.Uuid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !unique {
+		t.Fatalf("got false, want true")
+	}
+}
+
+func TestRemoveOfNotUniquePrefixRemovesNothing(t *testing.T) {
+	ts1, _ := NewDefault("test1")
+	ts2, _ := NewDefault("test2")
+	ts2.uuid = ts1.uuid[:8] + ts2.uuid[8:]
+	useMemoryStore(t, ts1, ts2)
+	if err := Remove(ts1.Uuid()[:8]); err != ErrUuidNotUnique {
+		t.Fatalf("got %v, want %v", err, ErrUuidNotUnique)
+	}
+	tasks, err := LoadTasks()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+}
+
+func TestRemoveOfUniquePrefixRemovesTheTask(t *testing.T) {
+	ts1, _ := NewDefault("test1")
+	ts2, _ := NewDefault("test2")
+	useMemoryStore(t, ts1, ts2)
+	if err := Remove(ts1.Uuid()[:10]); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err := LoadTasks()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 1 || tasks[0].Uuid() != ts2.Uuid() {
+		t.Fatalf("expected only %s left", ts2.Uuid())
+	}
+}
+
+func TestLoadTaskOfMissingUuidReturnsError(t *testing.T) {
+	useMemoryStore(t)
+	if _, err := LoadTask("abc"); err != ErrTaskNotFound {
+		t.Fatalf("got %v, want %v", err, ErrTaskNotFound)
+	}
+}
+
+func TestFileStoreLoadsSavesAndRemovesTasks(t *testing.T) {
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	store := NewFileStore(dirname)
+	ts, _ := NewDefault("test")
+	if err = store.Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err := store.Load(ts.Uuid()[:3])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(tasks) != 1 || tasks[0].Uuid() != ts.Uuid() {
+		t.Fatalf("expected to load %s", ts.Uuid())
+	}
+	if err = store.Remove(ts.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = store.Remove(ts.Uuid()); err != nil {
+		t.Fatalf("removing a missing task: %s", err.Error())
+	}
+	exists, err := store.Exists(ts.Uuid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if exists {
+		t.Fatalf("got true, want false")
+	}
+}
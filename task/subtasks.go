@@ -0,0 +1,200 @@
+package task
+
+import (
+	"errors"
+)
+
+var (
+	ErrSelfParent    = errors.New("a task can not be its own parent")
+	ErrParentCycle   = errors.New("parent would create a cycle")
+	ErrInvalidParent = errors.New("invalid parent uuid")
+)
+
+// Returns the uuid of the parent of the task, empty if it has none.
+func (t *Task) Parent() string {
+	return t.parent
+}
+
+// Makes this task a subtask of the task of given uuid, the empty string making
+// it a top level task. Returns an error if the uuid is the one of this task or
+// is invalid. Cycles are checked by Hierarchy.CheckParent.
+func (t *Task) SetParent(uuid string) error {
+	if len(uuid) > 255 {
+		return ErrInvalidParent
+	}
+	if uuid != "" && uuid == t.uuid {
+		return ErrSelfParent
+	}
+	t.parent = uuid
+	return nil
+}
+
+// A Hierarchy gives the parent and children relations between a set of tasks.
+// A task whose parent is not part of the hierarchy, for example because it was
+// filtered out, is a root of the hierarchy. So is the first task, in the given
+// order, of a cycle of parents, which can only come from damaged or hand
+// edited task files, so that every task is the descendant of a root.
+type Hierarchy struct {
+	tasks    []*Task            // the tasks, in the given order
+	byUuid   map[string]*Task   // the tasks by uuid
+	parents  map[string]string  // the parents of the tasks by uuid, cycles cut
+	children map[string][]*Task // the children of the tasks by uuid
+}
+
+// Returns the hierarchy of the given tasks. Roots and children keep the order
+// of the given tasks.
+func NewHierarchy(tasks []*Task) *Hierarchy {
+	h := &Hierarchy{
+		tasks:    tasks,
+		byUuid:   make(map[string]*Task, len(tasks)),
+		parents:  make(map[string]string, len(tasks)),
+		children: make(map[string][]*Task),
+	}
+	for _, t := range tasks {
+		h.byUuid[t.uuid] = t
+	}
+	for _, t := range tasks {
+		if _, ok := h.byUuid[t.parent]; ok {
+			h.parents[t.uuid] = t.parent
+		}
+	}
+	for _, t := range tasks {
+		if h.inCycle(t.uuid) {
+			delete(h.parents, t.uuid)
+		}
+	}
+	for _, t := range tasks {
+		if parent, ok := h.parents[t.uuid]; ok {
+			h.children[parent] = append(h.children[parent], t)
+		}
+	}
+	return h
+}
+
+// Returns true if the task of given uuid is its own ancestor.
+func (h *Hierarchy) inCycle(uuid string) bool {
+	visited := make(map[string]bool)
+	for cur, ok := h.parents[uuid]; ok; cur, ok = h.parents[cur] {
+		if cur == uuid {
+			return true
+		}
+		if visited[cur] {
+			break
+		}
+		visited[cur] = true
+	}
+	return false
+}
+
+// Returns the tasks of the hierarchy whose parent is not part of it.
+func (h *Hierarchy) Roots() []*Task {
+	var res []*Task
+	for _, t := range h.tasks {
+		if _, ok := h.parents[t.uuid]; !ok {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// Returns the children of the task of given uuid.
+func (h *Hierarchy) Children(uuid string) []*Task {
+	return h.children[uuid]
+}
+
+// Returns the children of the task of given uuid, their children and so on,
+// each task being followed by its descendants.
+func (h *Hierarchy) Descendants(uuid string) []*Task {
+	var res []*Task
+	for _, child := range h.children[uuid] {
+		res = append(res, child)
+		res = append(res, h.Descendants(child.uuid)...)
+	}
+	return res
+}
+
+// Returns the descendants of the task of given uuid that are not done.
+func (h *Hierarchy) OpenDescendants(uuid string) []*Task {
+	var res []*Task
+	for _, t := range h.Descendants(uuid) {
+		if t.status != Done {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// Returns the number of done descendants of the task of given uuid, and its
+// number of descendants.
+func (h *Hierarchy) Progress(uuid string) (int, int) {
+	descendants := h.Descendants(uuid)
+	return len(descendants) - len(h.OpenDescendants(uuid)), len(descendants)
+}
+
+// Returns nil if the given task can be made a subtask of the task of given
+// uuid, that is if it is another task of the hierarchy that is not one of its
+// descendants. The given task does not need to be part of the hierarchy.
+func (h *Hierarchy) CheckParent(t *Task, uuid string) error {
+	if uuid == t.uuid {
+		return ErrSelfParent
+	}
+	if _, ok := h.byUuid[uuid]; !ok {
+		return ErrTaskNotFound
+	}
+	// the parents given by the tasks are followed rather than those of the
+	// hierarchy, whose cycles are cut
+	visited := make(map[string]bool)
+	for cur := uuid; cur != "" && !visited[cur]; cur = h.byUuid[cur].parent {
+		if cur == t.uuid {
+			return ErrParentCycle
+		}
+		if _, ok := h.byUuid[cur]; !ok {
+			break
+		}
+		visited[cur] = true
+	}
+	return nil
+}
+
+// Drops the parent of the given task, about to be saved in the given store, if
+// it would make a cycle with the parents of the tasks of the store, which is
+// logged. A parent missing from the store is kept.
+func dropCyclicParent(store Store, t *Task) error {
+	if t.parent == "" {
+		return nil
+	}
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+	if NewHierarchy(tasks).CheckParent(t, t.parent) == ErrParentCycle {
+		logf("%s no longer has parent %s, which would make a cycle", t.uuid,
+			t.parent)
+		t.parent = ""
+	}
+	return nil
+}
+
+// Makes the children of the task of given uuid of the given store children of
+// its parent, and saves them. Returns the changed tasks. This is meant to be
+// called before the task is removed.
+func Reparent(store Store, uuid string) ([]*Task, error) {
+	tasks, err := store.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	h := NewHierarchy(tasks)
+	parent := h.byUuid[uuid]
+	if parent == nil {
+		return nil, ErrTaskNotFound
+	}
+	children := h.Children(uuid)
+	for _, child := range children {
+		child.parent = parent.parent
+		child.Touch()
+		if err = store.Save(child); err != nil {
+			return nil, err
+		}
+	}
+	return children, nil
+}
@@ -0,0 +1,109 @@
+package task
+
+import (
+	"slices"
+	"testing"
+)
+
+// Returns a task of given title and status, subtask of the given parent if not
+// nil.
+func newSubtask(t *testing.T, title string, status byte, parent *Task) *Task {
+	ts, err := NewTask(title, "", false, Medium, status)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if parent != nil {
+		if err = ts.SetParent(parent.Uuid()); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	return ts
+}
+
+func TestSetParentToItselfReturnsError(t *testing.T) {
+	ts := newSubtask(t, "test", Todo, nil)
+	if err := ts.SetParent(ts.Uuid()); err != ErrSelfParent {
+		t.Fatalf("got %v, want %v", err, ErrSelfParent)
+	}
+}
+
+func TestHierarchyRootsChildrenAndProgress(t *testing.T) {
+	root := newSubtask(t, "root", Todo, nil)
+	a := newSubtask(t, "a", Done, root)
+	b := newSubtask(t, "b", Todo, root)
+	c := newSubtask(t, "c", Done, b)
+	orphan := newSubtask(t, "orphan", Todo, nil)
+	orphan.SetParent("filtered-out")
+	h := NewHierarchy([]*Task{c, root, a, orphan, b})
+	if !slices.Equal(h.Roots(), []*Task{root, orphan}) {
+		t.Fatalf("got %d roots, want root and orphan", len(h.Roots()))
+	}
+	if !slices.Equal(h.Children(root.Uuid()), []*Task{a, b}) {
+		t.Fatalf("got %d children, want a and b", len(h.Children(root.Uuid())))
+	}
+	if !slices.Equal(h.Descendants(root.Uuid()), []*Task{a, b, c}) {
+		t.Fatalf("got wrong descendants")
+	}
+	done, total := h.Progress(root.Uuid())
+	if done != 2 || total != 3 {
+		t.Fatalf("got %d/%d, want 2/3", done, total)
+	}
+	if !slices.Equal(h.OpenDescendants(root.Uuid()), []*Task{b}) {
+		t.Fatalf("got wrong open descendants")
+	}
+}
+
+func TestCheckParentRejectsCycles(t *testing.T) {
+	root := newSubtask(t, "root", Todo, nil)
+	child := newSubtask(t, "child", Todo, root)
+	grandchild := newSubtask(t, "grandchild", Todo, child)
+	h := NewHierarchy([]*Task{root, child, grandchild})
+	if err := h.CheckParent(root, grandchild.Uuid()); err != ErrParentCycle {
+		t.Fatalf("got %v, want %v", err, ErrParentCycle)
+	}
+	if err := h.CheckParent(grandchild, root.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := h.CheckParent(root, "missing"); err != ErrTaskNotFound {
+		t.Fatalf("got %v, want %v", err, ErrTaskNotFound)
+	}
+}
+
+func TestRemoveReparentsChildren(t *testing.T) {
+	root := newSubtask(t, "root", Todo, nil)
+	child := newSubtask(t, "child", Todo, root)
+	grandchild := newSubtask(t, "grandchild", Todo, child)
+	store := useMemoryStore(t, root, child, grandchild)
+	if err := Remove(child.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	tasks, err := store.Load(grandchild.Uuid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if tasks[0].Parent() != root.Uuid() {
+		t.Fatalf("got parent %s, want %s", tasks[0].Parent(), root.Uuid())
+	}
+}
+
+func TestHierarchyCutsParentCycles(t *testing.T) {
+	a := newSubtask(t, "a", Todo, nil)
+	b := newSubtask(t, "b", Done, a)
+	c := newSubtask(t, "c", Todo, b)
+	a.SetParent(c.Uuid())
+	d := newSubtask(t, "d", Todo, a)
+	h := NewHierarchy([]*Task{b, a, c, d})
+	if !slices.Equal(h.Roots(), []*Task{b}) {
+		t.Fatalf("got %d roots, want b", len(h.Roots()))
+	}
+	if !slices.Equal(h.Descendants(b.Uuid()), []*Task{c, a, d}) {
+		t.Fatalf("got wrong descendants")
+	}
+	done, total := h.Progress(b.Uuid())
+	if done != 0 || total != 3 {
+		t.Fatalf("got %d/%d, want 0/3", done, total)
+	}
+	if err := h.CheckParent(b, d.Uuid()); err != ErrParentCycle {
+		t.Fatalf("got %v, want %v", err, ErrParentCycle)
+	}
+}
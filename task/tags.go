@@ -0,0 +1,103 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	TagMaxLength = 64
+	TagsMaxCount = 255
+)
+
+var (
+	ErrInvalidTag  = errors.New("tag must be 1 to 64 characters, without spaces or commas, not starting with + or -")
+	ErrTooManyTags = errors.New("too many tags (max 255)")
+)
+
+// Returns nil if the given tag is valid: it is not empty, at most
+// TagMaxLength bytes long, holds no space nor comma and does not start with
+// '+' or '-', which denote tag filters.
+func checkTagValidity(tag string) error {
+	if tag == "" || len(tag) > TagMaxLength ||
+		strings.HasPrefix(tag, "+") || strings.HasPrefix(tag, "-") ||
+		strings.ContainsFunc(tag, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		}) {
+		return ErrInvalidTag
+	}
+	return nil
+}
+
+// Returns true if the given tag is valid.
+func IsValidTag(tag string) bool {
+	return checkTagValidity(tag) == nil
+}
+
+// Parses the strings and returns the tags of the "+tag" filters and of the
+// "-tag" filters found, without duplicates
+func ParseTagFiltersFrom(strs []string) ([]string, []string) {
+	var included, excluded []string
+	for _, str := range strs {
+		if len(str) < 2 || !IsValidTag(str[1:]) {
+			continue
+		}
+		switch str[0] {
+		case '+':
+			if !slices.Contains(included, str[1:]) {
+				included = append(included, str[1:])
+			}
+		case '-':
+			if !slices.Contains(excluded, str[1:]) {
+				excluded = append(excluded, str[1:])
+			}
+		}
+	}
+	return included, excluded
+}
+
+// Returns true if the task has at least one of the included tags, if any, and
+// none of the excluded tags
+func matchesTagFilters(t *Task, included, excluded []string) bool {
+	for _, tag := range excluded {
+		if t.HasTag(tag) {
+			return false
+		}
+	}
+	if len(included) == 0 {
+		return true
+	}
+	for _, tag := range included {
+		if t.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// A TagCount is a tag and the number of tasks having it.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// Returns the tags of the given tasks with their number of tasks, sorted by
+// tag.
+func CountTags(tasks []*Task) []TagCount {
+	counts := make(map[string]int)
+	for _, t := range tasks {
+		for _, tag := range t.tags {
+			counts[tag]++
+		}
+	}
+	res := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		res = append(res, TagCount{tag, count})
+	}
+	slices.SortFunc(res, func(a, b TagCount) int {
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return res
+}
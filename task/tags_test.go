@@ -0,0 +1,128 @@
+package task
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAddTagKeepsTagsSortedWithoutDuplicates(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, tag := range []string{"release-1.4", "backend", "release-1.4"} {
+		if err = ts.AddTag(tag); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	want := []string{"backend", "release-1.4"}
+	if !slices.Equal(ts.Tags(), want) {
+		t.Fatalf("got %v, want %v", ts.Tags(), want)
+	}
+	if !ts.HasTag("backend") || ts.HasTag("frontend") {
+		t.Fatalf("got wrong HasTag result")
+	}
+}
+
+func TestAddInvalidTagReturnsError(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	invalids := []string{"", "+a", "-a", "a b", "a,b", strings.Repeat("a", 65)}
+	for _, tag := range invalids {
+		if err = ts.AddTag(tag); err != ErrInvalidTag {
+			t.Fatalf("%q: got %v, want %v", tag, err, ErrInvalidTag)
+		}
+	}
+}
+
+func TestAddTagBeyondMaxCountReturnsError(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i := 0; i < TagsMaxCount; i++ {
+		if err = ts.AddTag("tag" + strconv.Itoa(i)); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	if err = ts.AddTag("last"); err != ErrTooManyTags {
+		t.Fatalf("got %v, want %v", err, ErrTooManyTags)
+	}
+}
+
+func TestRemoveTag(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.AddTag("a")
+	ts.AddTag("b")
+	if !ts.RemoveTag("a") {
+		t.Fatalf("expected tag a to be removed")
+	}
+	if ts.RemoveTag("a") {
+		t.Fatalf("expected tag a to be already removed")
+	}
+	if !slices.Equal(ts.Tags(), []string{"b"}) {
+		t.Fatalf("got %v, want [b]", ts.Tags())
+	}
+}
+
+func TestFilterTasksByTags(t *testing.T) {
+	backend, _ := NewDefault("backend")
+	backend.AddTag("backend")
+	blocked, _ := NewDefault("blocked")
+	blocked.AddTag("backend")
+	blocked.AddTag("blocked")
+	frontend, _ := NewTask("frontend", "", false, High, Todo)
+	frontend.AddTag("frontend")
+	tasks := []*Task{backend, blocked, frontend}
+	tests := []struct {
+		filters []string
+		want    []*Task
+	}{
+		{[]string{"+backend"}, []*Task{backend, blocked}},
+		{[]string{"+backend", "-blocked"}, []*Task{backend}},
+		{[]string{"+backend", "+frontend"}, tasks},
+		{[]string{"-backend"}, []*Task{frontend}},
+		{[]string{"+frontend", "medium"}, nil},
+		{[]string{"+unknown"}, nil},
+	}
+	for _, test := range tests {
+		got, err := FilterTasks(tasks, test.filters)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !slices.Equal(got, test.want) {
+			t.Fatalf("%v: got %d tasks, want %d", test.filters, len(got),
+				len(test.want))
+		}
+	}
+}
+
+func TestCountTags(t *testing.T) {
+	a, _ := NewDefault("a")
+	a.AddTag("x")
+	a.AddTag("y")
+	b, _ := NewDefault("b")
+	b.AddTag("x")
+	got := CountTags([]*Task{a, b})
+	want := []TagCount{{"x", 2}, {"y", 1}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDisplayOfTaskWithTagsShowsTags(t *testing.T) {
+	ts, _ := NewDefault("test")
+	ts.AddTag("b")
+	ts.AddTag("a")
+	exp := "[To do] test +a +b <medium> " + ts.Uuid()
+	if ts.Display() != exp {
+		t.Fatalf("got \"%s\", want \"%s\"", ts.Display(), exp)
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -20,11 +24,17 @@ const (
 	TitleMinLength = 1
 	TitleMaxLength = 255
 	DescMaxLength  = 65535
+
+	// The number of characters of the uuids shown by ShortDisplay
+	ShortUuidLength = 8
 )
 
 var (
 	TasksPath = ""
 
+	// The logger warnings of the package are written to, nil to discard them
+	Logger *log.Logger = nil
+
 	ErrTitleTooShort       = errors.New("title too short (min 1)")
 	ErrTitleTooLong        = errors.New("title too long (max 255)")
 	ErrInvalidPriority     = errors.New("priority must be Low, Medium or High")
@@ -32,18 +42,34 @@ var (
 	ErrDescTooLong         = errors.New("description too long (max 65535)")
 	ErrInvalidLoadPath     = errors.New("invalid load path")
 	ErrInvalidTaskFileSize = errors.New("invalid task file size")
+	ErrInvalidDue          = errors.New("not a valid due date")
+	ErrInvalidUuid         = errors.New("invalid uuid length")
+	ErrUuidNotUnique       = errors.New("uuid prefix not unique")
+	ErrTaskNotFound        = errors.New("task not found")
 )
 
 // A Task represents something to do before an arbitrary due date.
 type Task struct {
-	title      string // the title of the task (0 < length < 256)
-	desc       string // the description of the task (0 <= length <= 65535)
-	isPeriodic bool   // indicates if the task is periodic
-	priority   byte   // the priority of the task: Low(0), Medium(1), High(2)
-	status     byte   // the status of the task: Todo(3), Doing(4), Done(5)
-	uuid       string // the uuid of the task
+	title      string     // the title of the task (0 < length < 256)
+	desc       string     // the description of the task (0 <= length <= 65535)
+	recurrence Recurrence // the rule giving the next occurrences of the task
+	priority   byte       // the priority of the task: Low(0), Medium(1), High(2)
+	status     byte       // the status of the task: Todo(3), Doing(4), Done(5)
+	uuid       string     // the uuid of the task
+	due        time.Time  // the due date of the task, zero if it has none
+	tags       []string   // the sorted tags of the task, without duplicates
+	project    string     // the project path of the task, empty if it has none
+	deps       []string   // the sorted uuids of the tasks this task depends on
+	parent     string     // the uuid of the parent task, empty if it has none
+	created    time.Time  // the creation time of the task, zero if unknown
+	modified   time.Time  // the last modification time, zero if unknown
+	started    time.Time  // the time the task was first doing, zero if never
+	completed  time.Time  // the time the task was done, zero if it is not
+	deleted    time.Time  // the time the task was trashed, zero if it is not
 }
 
+// Returns a new task of given parameters. A periodic task recurs daily, see
+// SetRecurrence for other rules.
 func NewTask(title, desc string, isPeriodic bool, priority, status byte) (*Task,
 	error) {
 	if err := checkTitleValidity(title); err != nil {
@@ -59,13 +85,20 @@ func NewTask(title, desc string, isPeriodic bool, priority, status byte) (*Task,
 		return nil, ErrInvalidStatus
 	}
 	new := Task{
-		title:      title,
-		desc:       desc,
-		isPeriodic: isPeriodic,
-		priority:   priority,
-		status:     status,
-		uuid:       uuid.NewString(),
+		title:    title,
+		desc:     desc,
+		priority: priority,
+		status:   status,
+		uuid:     uuid.NewString(),
+		created:  now(),
 	}
+	new.modified = new.created
+	if status == Doing {
+		new.started = new.created
+	} else if status == Done {
+		new.completed = new.created
+	}
+	new.SetPeriodicity(isPeriodic)
 	return &new, nil
 }
 
@@ -78,7 +111,9 @@ func NewDefault(title string) (*Task, error) {
 		priority: Medium,
 		status:   Todo,
 		uuid:     uuid.NewString(),
+		created:  now(),
 	}
+	new.modified = new.created
 	return &new, nil
 }
 
@@ -94,7 +129,13 @@ func (t *Task) Description() string {
 
 // Returns true if the task is periodic.
 func (t *Task) IsPeriodic() bool {
-	return t.isPeriodic
+	return t.recurrence.kind != RecurNone
+}
+
+// Returns the recurrence rule of the task. Its kind is RecurNone if the task is
+// not periodic.
+func (t *Task) Recurrence() Recurrence {
+	return t.recurrence
 }
 
 // Returns the priority of the task. The priority is one of these values: Low,
@@ -114,6 +155,121 @@ func (t *Task) Uuid() string {
 	return t.uuid
 }
 
+// Returns the due date of the task. The returned time is zero if the task has
+// no due date.
+func (t *Task) Due() time.Time {
+	return t.due
+}
+
+// Returns true if the task has a due date.
+func (t *Task) HasDue() bool {
+	return !t.due.IsZero()
+}
+
+// Sets the due date of this task. A zero time removes the due date.
+func (t *Task) SetDue(due time.Time) {
+	if due.IsZero() {
+		t.due = time.Time{}
+		return
+	}
+	t.due = due.Truncate(time.Second)
+}
+
+// Returns true if the due date of the task has no time of day, that is if it
+// only denotes a day.
+func (t *Task) isDueDateOnly() bool {
+	return isDateOnly(t.due)
+}
+
+// Returns true if the given time is a midnight, which denotes a whole day.
+func isDateOnly(t time.Time) bool {
+	h, m, s := t.Clock()
+	return h == 0 && m == 0 && s == 0
+}
+
+// Returns true if the task has a due date that has passed at the given time
+// and the task is not done. A due date without a time of day passes at the end
+// of its day.
+func (t *Task) IsOverdue(now time.Time) bool {
+	if !t.HasDue() || t.status == Done {
+		return false
+	}
+	if t.isDueDateOnly() {
+		return startOfDay(t.due).Before(startOfDay(now))
+	}
+	return t.due.Before(now)
+}
+
+// Returns true if the task is due on the day of the given time.
+func (t *Task) IsDueToday(now time.Time) bool {
+	if !t.HasDue() {
+		return false
+	}
+	return startOfDay(t.due).Equal(startOfDay(now))
+}
+
+// Returns true if the task is due in the seven days starting at the day of the
+// given time.
+func (t *Task) IsDueThisWeek(now time.Time) bool {
+	if !t.HasDue() {
+		return false
+	}
+	day := startOfDay(t.due)
+	today := startOfDay(now)
+	return !day.Before(today) && day.Before(today.AddDate(0, 0, 7))
+}
+
+// Returns the midnight of the day of the given time, in its location.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Returns the due date of the task formatted for display: only the day if it
+// has no time of day, the day and the time otherwise.
+func (t *Task) FormatDue() string {
+	if t.isDueDateOnly() {
+		return t.due.Format(time.DateOnly)
+	}
+	return t.due.Format("2006-01-02 15:04")
+}
+
+// Parses the due date denoted by the given string, relatively to now. Accepted
+// forms are "2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04", "today",
+// "tomorrow", "+Nd" (in N days) and "+Nw" (in N weeks). Dates are read in the
+// location of now.
+func ParseDue(due string, now time.Time) (time.Time, error) {
+	today := startOfDay(now)
+	switch due {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+	if len(due) > 2 && due[0] == '+' {
+		n, err := strconv.Atoi(due[1 : len(due)-1])
+		if err != nil || n < 0 {
+			return time.Time{}, ErrInvalidDue
+		}
+		switch due[len(due)-1] {
+		case 'd':
+			return today.AddDate(0, 0, n), nil
+		case 'w':
+			return today.AddDate(0, 0, 7*n), nil
+		default:
+			return time.Time{}, ErrInvalidDue
+		}
+	}
+	layouts := []string{time.DateOnly, "2006-01-02 15:04", "2006-01-02T15:04"}
+	for _, layout := range layouts {
+		res, err := time.ParseInLocation(layout, due, now.Location())
+		if err == nil {
+			return res, nil
+		}
+	}
+	return time.Time{}, ErrInvalidDue
+}
+
 // Returns true if the given title is longer than the minimum title length
 func isTitleLongerThanMinLength(title string) bool {
 	return len(title) >= TitleMinLength
@@ -154,50 +310,24 @@ func (t *Task) saveAt(path string) error {
 		path = path + "/"
 	}
 	path = path + t.uuid
-	offset := 0
-	data := make([]byte, t.Length())
-	titleLen := len(t.Title())
-	data[offset] = byte(titleLen)
-	offset++
-	copy(data[offset:offset+titleLen], t.Title())
-	offset += titleLen
-	descLen := uint16(len(t.Description()))
-	data[offset] = byte(descLen >> 8)
-	offset++
-	data[offset] = byte(descLen)
-	offset++
-	copy(data[offset:offset+int(descLen)], t.Description())
-	offset += int(descLen)
-	if t.IsPeriodic() {
-		data[offset] = 1
-	} else {
-		data[offset] = 0
-	}
-	offset++
-	data[offset] = byte(t.Priority())
-	offset++
-	data[offset] = byte(t.Status())
-	offset++
-	uuidLen := len(t.uuid)
-	data[offset] = byte(uuidLen)
-	offset++
-	copy(data[offset:offset+uuidLen], t.uuid)
-	return os.WriteFile(filepath.Clean(path), data, 0644)
-}
-
-// Saves on disk this task. TasksPath must be set before the call. Returns an
-// error if something wrong happened
+	return writeFileAtomic(path, t.encode(), 0644)
+}
+
+// Saves this task in the default store, see DefaultStore. Returns an error if
+// something wrong happened
 func (t *Task) SaveOnDisk() error {
-	return t.saveAt(TasksPath)
+	t.Touch()
+	return defaultStore().Save(t)
 }
 
 // Returns the number of bytes needed to store this task.
 func (t *Task) Length() int {
-	return 1 + len(t.Title()) + 2 + len(t.Description()) + 4 + len(t.uuid)
+	return len(t.encode())
 }
 
 // Loads to memory the task denoted by the given filepath and returns a pointer
-// to it.
+// to it. A task file written in an older format version is rewritten in the
+// current version.
 func loadTaskFrom(path string) (*Task, error) {
 	if path == "" {
 		return nil, ErrInvalidLoadPath
@@ -206,6 +336,7 @@ func loadTaskFrom(path string) (*Task, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 	info, err := f.Stat()
 	if err != nil {
 		return nil, err
@@ -213,64 +344,37 @@ func loadTaskFrom(path string) (*Task, error) {
 	if !info.Mode().IsRegular() {
 		return nil, ErrInvalidLoadPath
 	}
-	size := info.Size()
-	if size < 1 {
+	if info.Size() < 1 {
 		return nil, ErrInvalidTaskFileSize
 	}
-	data := make([]byte, size)
-	n, err := f.Read(data)
+	data, err := io.ReadAll(f)
 	if err != nil {
 		return nil, err
 	}
-	data = data[:n]
-	if len(data) < 1 {
-		return nil, ErrInvalidTaskFileSize
-	}
-	offset := 0
-	titleLen := int(data[offset])
-	offset++
-	if len(data) < offset+titleLen {
-		return nil, ErrInvalidTaskFileSize
-	}
-	title := string(data[offset : offset+titleLen])
-	offset += titleLen
-	if len(data) < offset+2 {
-		return nil, ErrInvalidTaskFileSize
-	}
-	descLen := uint16(data[offset])<<8 + uint16(data[offset+1])
-	offset += 2
-	if len(data) < offset+int(descLen) {
-		return nil, ErrInvalidTaskFileSize
-	}
-	desc := string(data[offset : offset+int(descLen)])
-	offset += int(descLen)
-	if len(data) < offset+3 {
-		return nil, ErrInvalidTaskFileSize
-	}
-	isPeriodic := (data[offset] == 1)
-	offset++
-	priority := data[offset]
-	offset++
-	status := data[offset]
-	offset++
-	if len(data) < offset+2 {
-		return nil, ErrInvalidTaskFileSize
-	}
-	uuidLen := int(data[offset])
-	offset++
-	if len(data) < offset+uuidLen {
-		return nil, ErrInvalidTaskFileSize
-	}
-	uuid := string(data[offset : offset+uuidLen])
-	newTask, err := NewTask(title, desc, isPeriodic, priority, status)
+	newTask, version, err := decodeTask(data)
 	if err != nil {
 		return nil, err
 	}
-	newTask.uuid = uuid
+	if version < FormatVersion {
+		// the task is usable even if the upgrade fails, it is then retried
+		// on the next load
+		writeFileAtomic(path, newTask.encode(), info.Mode().Perm())
+	}
 	return newTask, nil
 }
 
-// Loads into a slice of Task pointers the tasks saved at the given path
+// Writes the given warning on Logger if it is set.
+func logf(format string, args ...any) {
+	if Logger != nil {
+		Logger.Printf(format, args...)
+	}
+}
+
+// Loads into a slice of Task pointers the tasks saved at the given path.
+// Temporary files of interrupted saves are skipped and removed, subdirectories
+// are skipped. Files that can not be parsed are moved to the quarantine
+// subdirectory and the loading goes on, files that can not be read for another
+// reason are skipped with a warning.
 func loadTasksFrom(path string) ([]*Task, error) {
 	if path == "" {
 		return nil, ErrInvalidLoadPath
@@ -292,18 +396,32 @@ func loadTasksFrom(path string) ([]*Task, error) {
 	}
 	var tasks []*Task
 	for _, entry := range entries {
+		if isTempFile(entry.Name()) {
+			removeLeftoverTempFile(path, entry)
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
 		ts, err := loadTaskFrom(filepath.Join(path, entry.Name()))
+		if err != nil && isCorruptionError(err) {
+			if qErr := quarantine(path, entry.Name(), err); qErr != nil {
+				logf("skipped %s: %s", entry.Name(), err)
+			}
+			continue
+		}
 		if err != nil {
-			return nil, err
+			logf("skipped %s: %s", entry.Name(), err)
+			continue
 		}
 		tasks = append(tasks, ts)
 	}
 	return tasks, nil
 }
 
-// Loads into a slice of Task pointers the tasks saved on disk
+// Loads into a slice of Task pointers the tasks of the default store
 func LoadTasks() ([]*Task, error) {
-	return loadTasksFrom(TasksPath)
+	return defaultStore().LoadAll()
 }
 
 // Counts the number of files that have the given prefix at the given directory
@@ -331,7 +449,8 @@ func countFilesWithPrefixAt(path, prefix string) (int, error) {
 	}
 	count := 0
 	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), prefix) {
+		if strings.HasPrefix(entry.Name(), prefix) && !entry.IsDir() &&
+			!isTempFile(entry.Name()) {
 			count++
 		}
 	}
@@ -350,24 +469,42 @@ func existsAt(path, uuid string) (bool, error) {
 	return res > 0, nil
 }
 
-// Returns true if a task of given uuid or part of it already exists on disk.
+// Returns true if a task of given uuid or part of it already exists in the
+// default store.
 func Exists(uuid string) (bool, error) {
-	return existsAt(TasksPath, uuid)
+	return defaultStore().Exists(uuid)
 }
 
 // Returns true if a task of given uuid or has given uuid as prefix exists and
 // that task is the only one that has the given uuid as a prefix.
 func ExistsAndIsUnique(uuid string) (bool, error) {
-	res, err := countFilesWithPrefixAt(TasksPath, uuid)
+	return existsAndIsUniqueIn(defaultStore(), uuid)
+}
+
+// Returns true if exactly one task of the given store has the given uuid as
+// prefix.
+func existsAndIsUniqueIn(store Store, uuid string) (bool, error) {
+	tasks, err := store.Load(uuid)
 	if err != nil {
 		return false, err
 	}
-	return res == 1, nil
+	return len(tasks) == 1, nil
 }
 
 // Returns a string that displays the title, the status and the priority of this
 // task
 func (t *Task) Display() string {
+	return t.display(t.Uuid())
+}
+
+// Returns the display of this task with its uuid shortened to its first
+// ShortUuidLength characters.
+func (t *Task) ShortDisplay() string {
+	return t.display(t.Uuid()[:min(ShortUuidLength, len(t.Uuid()))])
+}
+
+// Returns the display of this task showing the given uuid.
+func (t *Task) display(uuid string) string {
 	prioDisp := ""
 	switch t.Priority() {
 	case Low:
@@ -386,8 +523,67 @@ func (t *Task) Display() string {
 	default:
 		statusDisp = "Done"
 	}
-	return fmt.Sprintf("[%s] %s <%s> %s", statusDisp, t.Title(), prioDisp,
-		t.Uuid())
+	dueDisp := ""
+	if t.IsOverdue(time.Now()) {
+		dueDisp = "overdue " + t.FormatDue()
+	} else if t.HasDue() {
+		dueDisp = "due " + t.FormatDue()
+	}
+	if t.IsPeriodic() {
+		if dueDisp != "" {
+			dueDisp += ", "
+		}
+		dueDisp += "repeats " + t.recurrence.String()
+	}
+	if dueDisp != "" {
+		dueDisp = " (" + dueDisp + ")"
+	}
+	tagsDisp := ""
+	if t.project != "" {
+		tagsDisp += " @" + t.project
+	}
+	for _, tag := range t.tags {
+		tagsDisp += " +" + tag
+	}
+	return fmt.Sprintf("[%s] %s%s <%s>%s %s", statusDisp, t.Title(), tagsDisp,
+		prioDisp, dueDisp, uuid)
+}
+
+// Returns the lines of the given text wrapped at the given width, breaking
+// lines between words, or not wrapped if the width is not positive. The line
+// breaks of the text are kept, the spaces between words are collapsed and
+// words longer than the width are not broken. An empty text has no lines.
+func WrapText(text string, width int) []string {
+	if text == "" {
+		return nil
+	}
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if width > 0 && line != "" &&
+				len([]rune(line))+1+len([]rune(word)) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Sets the title of this task to the given title. Returns an error if the
+// title is too short or too long.
+func (t *Task) SetTitle(newTitle string) error {
+	if err := checkTitleValidity(newTitle); err != nil {
+		return err
+	}
+	t.title = newTitle
+	return nil
 }
 
 // Sets the description of this task to the given description. If the
@@ -400,9 +596,115 @@ func (t *Task) SetDescription(newDesc string) error {
 	return nil
 }
 
-// Sets the periodicity of this task
+// Sets the periodicity of this task. A task that becomes periodic recurs daily,
+// a task that already is periodic keeps its recurrence rule.
 func (t *Task) SetPeriodicity(newPeriod bool) {
-	t.isPeriodic = newPeriod
+	if !newPeriod {
+		t.recurrence = Recurrence{}
+	} else if !t.IsPeriodic() {
+		t.recurrence = Daily()
+	}
+}
+
+// Sets the recurrence rule of this task. A rule of kind RecurNone makes the
+// task not periodic.
+func (t *Task) SetRecurrence(rule Recurrence) {
+	t.recurrence = rule
+}
+
+// Returns the sorted tags of the task.
+func (t *Task) Tags() []string {
+	return slices.Clone(t.tags)
+}
+
+// Returns true if the task has the given tag.
+func (t *Task) HasTag(tag string) bool {
+	_, found := slices.BinarySearch(t.tags, tag)
+	return found
+}
+
+// Adds the given tag to this task, if it does not have it yet. Returns an
+// error if the tag is invalid or if the task already has TagsMaxCount tags.
+func (t *Task) AddTag(tag string) error {
+	if err := checkTagValidity(tag); err != nil {
+		return err
+	}
+	i, found := slices.BinarySearch(t.tags, tag)
+	if found {
+		return nil
+	}
+	if len(t.tags) == TagsMaxCount {
+		return ErrTooManyTags
+	}
+	t.tags = slices.Insert(t.tags, i, tag)
+	return nil
+}
+
+// Removes the given tag from this task. Returns false if the task does not
+// have it.
+func (t *Task) RemoveTag(tag string) bool {
+	i, found := slices.BinarySearch(t.tags, tag)
+	if found {
+		t.tags = slices.Delete(t.tags, i, i+1)
+	}
+	return found
+}
+
+// Returns the current time, at the precision of the task files.
+func now() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+// Returns the creation time of the task, zero for the tasks created before
+// creation times were recorded.
+func (t *Task) Created() time.Time {
+	return t.created
+}
+
+// Returns the project path of the task, empty if it has none.
+func (t *Task) Project() string {
+	return t.project
+}
+
+// Sets the project path of this task, the empty string removing it from its
+// project. Returns an error if the path is invalid.
+func (t *Task) SetProject(project string) error {
+	if project != "" && !IsValidProject(project) {
+		return ErrInvalidProject
+	}
+	t.project = project
+	return nil
+}
+
+// Moves this periodic task to its next occurrence: its status is set back to
+// Todo, clearing its completion time, and its due date to the first
+// occurrence of its recurrence rule that is after now. A task without due
+// date recurs from the day of now. Returns false and leaves the task
+// unchanged if it is not periodic.
+func (t *Task) Reschedule(now time.Time) bool {
+	if !t.IsPeriodic() {
+		return false
+	}
+	next := t.due
+	if next.IsZero() {
+		next = startOfDay(now)
+	}
+	next = t.recurrence.Next(next)
+	for !occursAfter(next, now) {
+		next = t.recurrence.Next(next)
+	}
+	t.due = next
+	t.SetStatus(Todo)
+	return true
+}
+
+// Returns true if the given occurrence is after now. An occurrence without a
+// time of day is after now if it is on a later day.
+func occursAfter(occurrence, now time.Time) bool {
+	if isDateOnly(occurrence) {
+		return startOfDay(occurrence).After(startOfDay(now))
+	}
+	return occurrence.After(now)
 }
 
 // Returns true if the given description is valid
@@ -430,11 +732,22 @@ func (t *Task) SetPriority(newPrio byte) error {
 	return nil
 }
 
-// Sets the new status of this task. Returns an error if the status is not valid
+// Sets the new status of this task. Returns an error if the status is not valid.
+// The start time is set the first time the task is marked as doing, and the
+// completion time when it is marked as done, being unset when it is marked as
+// to do or doing again.
 func (t *Task) SetStatus(newStatus byte) error {
 	if !isValidStatus(newStatus) {
 		return ErrInvalidStatus
 	}
+	if newStatus == Doing && t.started.IsZero() {
+		t.started = now()
+	}
+	if newStatus == Done && t.status != Done {
+		t.completed = now()
+	} else if newStatus != Done {
+		t.completed = time.Time{}
+	}
 	t.status = newStatus
 	return nil
 }
@@ -457,14 +770,24 @@ func ParseStatus(status string) (byte, error) {
 // Loads the task of given uuid or part of it. If the task does not exist or
 // something happens during the load, returns an error.
 func LoadTask(uuid string) (*Task, error) {
+	return loadTaskIn(defaultStore(), uuid)
+}
+
+// Loads the task of the given store of given uuid or part of it. If several
+// tasks have the given prefix, the first one found is returned.
+func loadTaskIn(store Store, uuid string) (*Task, error) {
 	uuidLen := len(uuid)
 	if uuidLen == 0 || uuidLen > 36 {
-		return nil, errors.New("invalid uuid length")
+		return nil, ErrInvalidUuid
+	}
+	tasks, err := store.Load(uuid)
+	if err != nil {
+		return nil, err
 	}
-	if uuidLen < 36 {
-		return loadTaskFromPartialUuid(uuid)
+	if len(tasks) == 0 {
+		return nil, ErrTaskNotFound
 	}
-	return loadTaskFrom(filepath.Join(TasksPath, uuid))
+	return tasks[0], nil
 }
 
 // Parses the priority denoted by the given string. If priority is different
@@ -482,53 +805,75 @@ func ParsePriority(priority string) (byte, error) {
 	}
 }
 
-// Removes the file of given name at the given path if it exists, otherwise
-// returns an error.
-func removeAt(path, name string) error {
-	exists, err := Exists(name)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return os.Remove(filepath.Join(path, name))
+// Returns the name of the given status, as parsed by ParseStatus: "todo",
+// "doing" or "done".
+func StatusName(status int) string {
+	switch status {
+	case Todo:
+		return "todo"
+	case Doing:
+		return "doing"
+	default:
+		return "done"
 	}
-	return nil
 }
 
-// Searchs for the full uuid if it is partial and removes the corresponding
-// task. If an error occurs, it returns the error.
-func removeFromPartialUuid(uuid string) error {
-	taskDir, err := os.ReadDir(TasksPath)
-	if err != nil {
-		return err
-	}
-	for _, entry := range taskDir {
-		filename := entry.Name()
-		if strings.HasPrefix(filename, uuid) {
-			return removeAt(TasksPath, filename)
-		}
+// Returns the name of the given priority, as parsed by ParsePriority: "low",
+// "medium" or "high".
+func PriorityName(priority int) string {
+	switch priority {
+	case Low:
+		return "low"
+	case Medium:
+		return "medium"
+	default:
+		return "high"
 	}
-	return errors.New("task not found")
 }
 
 // Removes the task of given uuid or part of if. If multiple tasks have the
-// given uuid as prefix, no tasks are removed and an error is returned.
+// given uuid as prefix, no tasks are removed and an error is returned. The
+// tasks depending on the removed task no longer depend on it, and its subtasks
+// become subtasks of its parent, a warning being logged for each of them. The
+// removed task is moved to DefaultTrash, if set.
 func Remove(uuid string) error {
-	existsAndUnique, err := ExistsAndIsUnique(uuid)
+	return removeIn(defaultStore(), uuid)
+}
+
+// Removes the task of the given store of given uuid or part of it, as Remove
+// does.
+func removeIn(store Store, uuid string) error {
+	uuidLen := len(uuid)
+	if uuidLen == 0 || uuidLen > 36 {
+		return ErrInvalidUuid
+	}
+	tasks, err := store.Load(uuid)
 	if err != nil {
 		return err
 	}
-	if !existsAndUnique {
-		return errors.New("uuid prefix not unique")
+	if len(tasks) != 1 {
+		return ErrUuidNotUnique
 	}
-	uuidLen := len(uuid)
-	if uuidLen == 0 || uuidLen > 36 {
-		return errors.New("invalid uuid length")
+	children, err := Reparent(store, tasks[0].uuid)
+	if err != nil {
+		return err
 	}
-	if uuidLen < 36 {
-		return removeFromPartialUuid(uuid)
+	for _, t := range children {
+		logf("%s moved to the parent of removed task %s", t.uuid, tasks[0].uuid)
 	}
-	return removeAt(TasksPath, uuid)
+	if DefaultTrash != nil {
+		if err = DefaultTrash.Put(tasks[0]); err != nil {
+			return err
+		}
+	}
+	if err = store.Remove(tasks[0].uuid); err != nil {
+		return err
+	}
+	dependents, err := RemoveDependencies(store, tasks[0].uuid)
+	for _, t := range dependents {
+		logf("%s no longer depends on removed task %s", t.uuid, tasks[0].uuid)
+	}
+	return err
 }
 
 // Parses the strings and returns the slice of status marks found, without
@@ -567,6 +912,18 @@ func ParsePriorityFrom(strings []string) ([]byte, error) {
 	return res, nil
 }
 
+// Parses the strings and returns the slice of due filters found, without
+// duplicates
+func ParseDueFilterFrom(strings []string) []string {
+	var res []string
+	for _, str := range strings {
+		if IsValidDueFilter(str) && !slices.Contains(res, str) {
+			res = append(res, str)
+		}
+	}
+	return res
+}
+
 // Returns true if status equals one of "todo", "doing" or "done"
 func IsValidStatus(status string) bool {
 	return status == "todo" || status == "doing" || status == "done"
@@ -577,43 +934,139 @@ func IsValidPriority(priority string) bool {
 	return priority == "low" || priority == "medium" || priority == "high"
 }
 
+// Returns true if filter equals one of "overdue", "today" or "week"
+func IsValidDueFilter(filter string) bool {
+	return filter == "overdue" || filter == "today" || filter == "week"
+}
+
+// Returns true if the task satisfies at least one of the given due filters at
+// the given time
+func matchesDueFilters(t *Task, filters []string, now time.Time) bool {
+	for _, filter := range filters {
+		switch filter {
+		case "overdue":
+			if t.IsOverdue(now) {
+				return true
+			}
+		case "today":
+			if t.IsDueToday(now) {
+				return true
+			}
+		case "week":
+			if t.IsDueThisWeek(now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Filters the given tasks and returns the remaining tasks
 func FilterTasks(tasks []*Task, filters []string) ([]*Task, error) {
-	sFilters, err := ParseStatusFrom(filters)
-	if err != nil {
-		return nil, err
-	}
-	pFilters, err := ParsePriorityFrom(filters)
+	return filterTasksAt(tasks, filters, time.Now())
+}
+
+// Filters the given tasks as FilterTasks does, evaluating the due filters at
+// the given time
+func filterTasksAt(tasks []*Task, filters []string, now time.Time) ([]*Task,
+	error) {
+	f, err := parseWordFilters(filters)
 	if err != nil {
 		return nil, err
 	}
-	if len(sFilters) == 0 && len(pFilters) == 0 {
+	if f.isEmpty() {
 		return tasks, nil
 	}
+	// the dependencies are looked up among the given tasks
+	graph := NewDependencyGraph(tasks)
 	var res []*Task
 	for _, task := range tasks {
-		if len(sFilters) != 0 && !slices.Contains(sFilters, task.status) {
-			continue
-		}
-		if len(pFilters) != 0 && !slices.Contains(pFilters, task.priority) {
-			continue
+		if f.match(task, graph, now) {
+			res = append(res, task)
 		}
-		res = append(res, task)
 	}
 	return res, nil
 }
 
-func loadTaskFromPartialUuid(uuid string) (*Task, error) {
-	taskDir, err := os.ReadDir(TasksPath)
+// The filters of FilterTasks, by category.
+type wordFilters struct {
+	statuses   []byte   // the status filters
+	priorities []byte   // the priority filters
+	dues       []string // the due filters
+	included   []string // the tags of the +tag filters
+	excluded   []string // the tags of the -tag filters
+	projects   []string // the projects of the project filters
+	deps       []string // the dependency filters
+	times      []string // the time filters
+}
+
+// Returns the filters of FilterTasks found in the given strings.
+func parseWordFilters(filters []string) (*wordFilters, error) {
+	sFilters, err := ParseStatusFrom(filters)
 	if err != nil {
 		return nil, err
 	}
-	for _, entry := range taskDir {
-		filename := entry.Name()
-		if strings.HasPrefix(filename, uuid) {
-			path := filepath.Join(TasksPath, filename)
-			return loadTaskFrom(path)
-		}
+	pFilters, err := ParsePriorityFrom(filters)
+	if err != nil {
+		return nil, err
+	}
+	f := &wordFilters{
+		statuses:   sFilters,
+		priorities: pFilters,
+		dues:       ParseDueFilterFrom(filters),
+		projects:   ParseProjectFiltersFrom(filters),
+		deps:       ParseDependencyFilterFrom(filters),
+		times:      ParseTimeFiltersFrom(filters),
 	}
-	return nil, errors.New("task not found")
+	f.included, f.excluded = ParseTagFiltersFrom(filters)
+	return f, nil
+}
+
+// Returns true if there is no filter.
+func (f *wordFilters) isEmpty() bool {
+	return len(f.statuses) == 0 && len(f.priorities) == 0 &&
+		len(f.dues) == 0 && len(f.included) == 0 && len(f.excluded) == 0 &&
+		len(f.projects) == 0 && len(f.deps) == 0 && len(f.times) == 0
+}
+
+// Returns true if the task matches the filters at the given time, its
+// dependencies being looked up in the given graph.
+func (f *wordFilters) match(task *Task, graph *DependencyGraph,
+	now time.Time) bool {
+	if len(f.statuses) != 0 && !slices.Contains(f.statuses, task.status) {
+		return false
+	}
+	if len(f.priorities) != 0 && !slices.Contains(f.priorities, task.priority) {
+		return false
+	}
+	if len(f.dues) != 0 && !matchesDueFilters(task, f.dues, now) {
+		return false
+	}
+	if !matchesTagFilters(task, f.included, f.excluded) {
+		return false
+	}
+	if len(f.projects) != 0 && !matchesProjectFilters(task, f.projects) {
+		return false
+	}
+	if len(f.deps) != 0 && !matchesDependencyFilters(graph, task, f.deps) {
+		return false
+	}
+	if len(f.times) != 0 && !matchesTimeFilters(task, f.times, now) {
+		return false
+	}
+	return true
+}
+
+// Returns true if the given string is a filter of FilterTasks.
+func isFilterWord(word string) bool {
+	if IsValidStatus(word) || IsValidPriority(word) ||
+		IsValidDueFilter(word) || IsValidDependencyFilter(word) ||
+		IsValidTimeFilter(word) {
+		return true
+	}
+	if len(word) >= 2 && (word[0] == '+' || word[0] == '-') {
+		return IsValidTag(word[1:])
+	}
+	project, ok := strings.CutPrefix(word, "project:")
+	return ok && IsValidProject(project)
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"path/filepath"
 	"slices"
+	"time"
 )
 
 // Returns true if s1 and s2 are exactly the same
@@ -247,7 +248,7 @@ func TestLengthNewDefaultTask(t *testing.T) {
 		t.Fatalf(err.Error())
 	}
 	l := ts.Length()
-	rl := 1 + 4 + 2 + 0 + 1 + 1 + 1 + 1 + 36
+	rl := 1 + 4 + 2 + 0 + 1 + 1 + 1 + 1 + 36 + 9 + 5 + 5 + 1 + 1 + 1 + 1 + 9 + 9 + 9 + 9 + 9
 	if l != rl {
 		t.Fatalf("got %d, want %d", l, rl)
 	}
@@ -259,7 +260,7 @@ func TestLengthNewTask(t *testing.T) {
 		t.Fatalf(err.Error())
 	}
 	l := ts.Length()
-	rl := 1 + 4 + 2 + 19 + 1 + 1 + 1 + 1 + 36
+	rl := 1 + 4 + 2 + 19 + 1 + 1 + 1 + 1 + 36 + 9 + 5 + 5 + 1 + 1 + 1 + 1 + 9 + 9 + 9 + 9 + 9
 	if l != rl {
 		t.Fatalf("got %d, want %d", l, rl)
 	}
@@ -279,7 +280,11 @@ func TestSaveAtSavesTheTaskAtPathAndTheTaskContent(t *testing.T) {
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
-	offset := 0
+	if string(data[:4]) != "\x00AGN" || data[4] != FormatVersion {
+		t.Fatalf("got header %v, want magic and version %d", data[:5],
+			FormatVersion)
+	}
+	offset := 5
 	titleLen := int(data[offset])
 	offset++
 	savedTitle := string(data[offset:offset+titleLen])
@@ -528,6 +533,18 @@ func TestListDisplayOfDefaultTaskShowsGoodTitleStatusAndPriority(t *testing.T) {
 	}
 }
 
+func TestShortDisplayShowsTheFirstCharactersOfTheUuid(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	display := ts.ShortDisplay()
+	exp := "[To do] test <medium> " + ts.Uuid()[:ShortUuidLength]
+	if display != exp {
+		t.Fatalf("got \"%s\", want \"%s\"", display, exp)
+	}
+}
+
 func TestSetDescriptionTooLongReturnsError(t *testing.T) {
 	ts, err := NewDefault("test")
 	if err != nil {
@@ -796,3 +813,291 @@ func TestSaveAndLoadNewTaskKeepsSameUUID(t *testing.T) {
 		t.Fatalf("got %s, wanted %s", savedTask.Uuid(), uuid)
 	}
 }
+
+func TestSaveAndLoadTaskKeepsDueDate(t *testing.T) {
+	ts, err := NewDefault("due")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	due := time.Date(2026, time.November, 1, 14, 30, 0, 0, time.Local)
+	ts.SetDue(due)
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	if err = ts.saveAt(dirname); err != nil {
+		t.Fatalf(err.Error())
+	}
+	loaded, err := loadTaskFrom(filepath.Join(dirname, ts.Uuid()))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !loaded.Due().Equal(due) {
+		t.Fatalf("got %v, want %v", loaded.Due(), due)
+	}
+}
+
+func TestLoadTaskWrittenWithoutDueDateHasNoDueDate(t *testing.T) {
+	ts, err := NewTask("old", "a desc", false, Low, Todo)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	path := filepath.Join(dirname, ts.Uuid())
+	if err = os.WriteFile(path, encodeVersion0(ts, 0), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	loaded, err := loadTaskFrom(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if loaded.HasDue() {
+		t.Fatalf("got due date %v, want none", loaded.Due())
+	}
+	if loaded.Uuid() != ts.Uuid() {
+		t.Fatalf("got %s, want %s", loaded.Uuid(), ts.Uuid())
+	}
+}
+
+func TestParseDue(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 10, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"2026-11-01":       time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+		"2026-11-01 15:04": time.Date(2026, time.November, 1, 15, 4, 0, 0, time.UTC),
+		"2026-11-01T15:04": time.Date(2026, time.November, 1, 15, 4, 0, 0, time.UTC),
+		"today":            time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC),
+		"tomorrow":         time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+		"+3d":              time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC),
+		"+2w":              time.Date(2026, time.October, 31, 0, 0, 0, 0, time.UTC),
+	}
+	for str, want := range cases {
+		got, err := ParseDue(str, now)
+		if err != nil {
+			t.Fatalf("%s: %s", str, err.Error())
+		}
+		if !got.Equal(want) {
+			t.Fatalf("%s: got %v, want %v", str, got, want)
+		}
+	}
+}
+
+func TestParseInvalidDueReturnsError(t *testing.T) {
+	now := time.Now()
+	for _, str := range []string{"", "soon", "+d", "+3m", "2026-13-01"} {
+		if _, err := ParseDue(str, now); err != ErrInvalidDue {
+			t.Fatalf("%s: got %v, want %v", str, err, ErrInvalidDue)
+		}
+	}
+}
+
+func TestDateOnlyDueDateIsOverdueTheDayAfter(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetDue(time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC))
+	if ts.IsOverdue(time.Date(2026, time.October, 17, 23, 0, 0, 0, time.UTC)) {
+		t.Fatalf("got overdue on the due day")
+	}
+	if !ts.IsOverdue(time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("got not overdue the day after")
+	}
+	ts.SetStatus(Done)
+	if ts.IsOverdue(time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("got done task overdue")
+	}
+}
+
+func TestFilterTasksByDue(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 10, 0, 0, 0, time.UTC)
+	late, _ := NewDefault("late")
+	late.SetDue(now.Add(-time.Hour))
+	today, _ := NewDefault("today")
+	today.SetDue(now.Add(time.Hour))
+	soon, _ := NewDefault("soon")
+	soon.SetDue(now.AddDate(0, 0, 3))
+	later, _ := NewDefault("later")
+	later.SetDue(now.AddDate(0, 1, 0))
+	none, _ := NewDefault("none")
+	tasks := []*Task{late, today, soon, later, none}
+	cases := map[string][]*Task{
+		"overdue": {late},
+		"today":   {late, today},
+		"week":    {late, today, soon},
+	}
+	for filter, want := range cases {
+		got, err := filterTasksAt(tasks, []string{filter}, now)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("%s: got %d tasks, want %d", filter, len(got), len(want))
+		}
+	}
+	got, err := filterTasksAt(tasks, []string{"overdue", "done"}, now)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(got) != 0 {
+		t.Fatalf("got %d tasks, want 0", len(got))
+	}
+}
+
+func TestDisplayOfTaskWithDueDateShowsDueDate(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetDue(time.Date(2999, time.November, 1, 0, 0, 0, 0, time.Local))
+	exp := "[To do] test <medium> (due 2999-11-01) " + ts.Uuid()
+	if ts.Display() != exp {
+		t.Fatalf("got \"%s\", want \"%s\"", ts.Display(), exp)
+	}
+}
+
+func TestLoadTaskWrittenWithPeriodicityFlagOnlyRecursDaily(t *testing.T) {
+	ts, err := NewTask("old", "", true, Low, Todo)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetRecurrence(Weekly(time.Monday))
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	path := filepath.Join(dirname, ts.Uuid())
+	if err = os.WriteFile(path, encodeVersion0(ts, 1), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	loaded, err := loadTaskFrom(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if loaded.Recurrence() != Daily() {
+		t.Fatalf("got %s, want %s", loaded.Recurrence(), Daily())
+	}
+}
+
+func TestSaveAndLoadTaskKeepsRecurrence(t *testing.T) {
+	ts, err := NewDefault("weekly")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	rule := Weekly(time.Monday, time.Friday)
+	ts.SetRecurrence(rule)
+	dirname, err := os.MkdirTemp("", "tempTasks")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dirname)
+	if err = ts.saveAt(dirname); err != nil {
+		t.Fatalf(err.Error())
+	}
+	loaded, err := loadTaskFrom(filepath.Join(dirname, ts.Uuid()))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if loaded.Recurrence() != rule {
+		t.Fatalf("got %s, want %s", loaded.Recurrence(), rule)
+	}
+}
+
+func TestRescheduleMovesDueDateAndResetsStatus(t *testing.T) {
+	ts, err := NewTask("periodic", "", true, Low, Done)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts.SetDue(time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2026, time.October, 17, 18, 0, 0, 0, time.UTC)
+	if !ts.Reschedule(now) {
+		t.Fatalf("got false, want true")
+	}
+	want := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	if !ts.Due().Equal(want) {
+		t.Fatalf("got %v, want %v", ts.Due(), want)
+	}
+	if ts.Status() != Todo {
+		t.Fatalf("got %d, want %d", ts.Status(), Todo)
+	}
+}
+
+func TestRescheduleOfLateTaskSkipsPastOccurrences(t *testing.T) {
+	ts, err := NewDefault("late")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	rule, _ := Every(3)
+	ts.SetRecurrence(rule)
+	ts.SetDue(time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC))
+	now := time.Date(2026, time.October, 17, 10, 0, 0, 0, time.UTC)
+	ts.Reschedule(now)
+	want := time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)
+	if !ts.Due().Equal(want) {
+		t.Fatalf("got %v, want %v", ts.Due(), want)
+	}
+}
+
+func TestRescheduleOfNotPeriodicTaskDoesNothing(t *testing.T) {
+	ts, err := NewTask("once", "", false, Low, Done)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if ts.Reschedule(time.Now()) {
+		t.Fatalf("got true, want false")
+	}
+	if ts.Status() != Done || ts.HasDue() {
+		t.Fatalf("expected unchanged task")
+	}
+}
+
+func TestSetTitleValidatesTitle(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = ts.SetTitle(""); err != ErrTitleTooShort {
+		t.Fatalf("got %v, want %v", err, ErrTitleTooShort)
+	}
+	if err = ts.SetTitle(strings.Repeat("a", 256)); err != ErrTitleTooLong {
+		t.Fatalf("got %v, want %v", err, ErrTitleTooLong)
+	}
+	if ts.Title() != "test" {
+		t.Fatalf("got \"%s\", want \"test\"", ts.Title())
+	}
+	if err = ts.SetTitle("renamed"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if ts.Title() != "renamed" {
+		t.Fatalf("got \"%s\", want \"renamed\"", ts.Title())
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"", 10, nil},
+		{"one two three", 7, []string{"one two", "three"}},
+		{"one two three", 13, []string{"one two three"}},
+		{"a verylongword b", 4, []string{"a", "verylongword", "b"}},
+		{"first\n\nsecond  line", 20, []string{"first", "", "second line"}},
+		{"été à la mer", 6, []string{"été à", "la mer"}},
+		{"one two three", 0, []string{"one two three"}},
+		{"one two three", -5, []string{"one two three"}},
+	}
+	for _, test := range tests {
+		got := WrapText(test.text, test.width)
+		if !slices.Equal(got, test.want) {
+			t.Fatalf("%q at %d: got %q, want %q", test.text, test.width, got,
+				test.want)
+		}
+	}
+}
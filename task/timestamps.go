@@ -0,0 +1,120 @@
+package task
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The fields holding the times tasks are filtered on by time filters.
+var timeFilterFields = []string{"created", "modified", "started", "completed"}
+
+// Returns the time the task was last modified, zero if unknown.
+func (t *Task) Modified() time.Time {
+	return t.modified
+}
+
+// Returns the time the task was first marked as doing, zero if it never was.
+func (t *Task) Started() time.Time {
+	return t.started
+}
+
+// Returns the time the task was last marked as done, zero if it never was or
+// if it was marked as to do or doing since. Rescheduling a periodic task to
+// its next occurrence clears its completion time.
+func (t *Task) Completed() time.Time {
+	return t.completed
+}
+
+// Sets the modification time of the task to now.
+func (t *Task) Touch() {
+	t.modified = now()
+}
+
+// Returns the time of given field of the task: "due", "created", "modified",
+// "started" or "completed". The time is zero if the task has none.
+func (t *Task) timeOf(field string) time.Time {
+	switch field {
+	case "due":
+		return t.due
+	case "created":
+		return t.created
+	case "modified":
+		return t.modified
+	case "started":
+		return t.started
+	default:
+		return t.completed
+	}
+}
+
+// Returns the start and the end of the time range of given name at the given
+// time, and false if the name is not a range. The ranges are "today",
+// "yesterday", "this-week" and "last-week" (weeks starting on monday),
+// "this-month", "last-month" and "Nd", the last N days up to now.
+func timeRange(name string, now time.Time) (time.Time, time.Time, bool) {
+	today := startOfDay(now)
+	// the number of days since the last monday
+	weekday := (int(today.Weekday()) + 6) % 7
+	month := today.AddDate(0, 0, 1-today.Day())
+	switch name {
+	case "today":
+		return today, today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, true
+	case "this-week":
+		start := today.AddDate(0, 0, -weekday)
+		return start, start.AddDate(0, 0, 7), true
+	case "last-week":
+		start := today.AddDate(0, 0, -weekday-7)
+		return start, start.AddDate(0, 0, 7), true
+	case "this-month":
+		return month, month.AddDate(0, 1, 0), true
+	case "last-month":
+		return month.AddDate(0, -1, 0), month, true
+	}
+	days, ok := strings.CutSuffix(name, "d")
+	n, err := strconv.Atoi(days)
+	if !ok || err != nil || n < 1 || days[0] == '+' {
+		return time.Time{}, time.Time{}, false
+	}
+	return now.AddDate(0, 0, -n), now.Add(time.Second), true
+}
+
+// Returns true if the given string is a time filter, a field among
+// "created", "modified", "started" and "completed" followed by a colon and a
+// time range of timeRange, such as "completed:last-week".
+func IsValidTimeFilter(filter string) bool {
+	field, name, ok := strings.Cut(filter, ":")
+	if !ok || !slices.Contains(timeFilterFields, field) {
+		return false
+	}
+	_, _, ok = timeRange(name, time.Now())
+	return ok
+}
+
+// Parses the strings and returns the time filters found, without duplicates
+func ParseTimeFiltersFrom(strs []string) []string {
+	var res []string
+	for _, str := range strs {
+		if IsValidTimeFilter(str) && !slices.Contains(res, str) {
+			res = append(res, str)
+		}
+	}
+	return res
+}
+
+// Returns true if the task satisfies at least one of the given time filters
+// at the given time: the time of the field of the filter is in its range.
+func matchesTimeFilters(t *Task, filters []string, now time.Time) bool {
+	for _, filter := range filters {
+		field, name, _ := strings.Cut(filter, ":")
+		start, end, ok := timeRange(name, now)
+		when := t.timeOf(field)
+		if ok && !when.IsZero() && !when.Before(start) && when.Before(end) {
+			return true
+		}
+	}
+	return false
+}
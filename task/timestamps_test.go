@@ -0,0 +1,138 @@
+package task
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSetStatusMaintainsTimestamps(t *testing.T) {
+	ts, err := NewDefault("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if ts.Modified() != ts.Created() || !ts.Started().IsZero() ||
+		!ts.Completed().IsZero() {
+		t.Fatalf("got %v, want only creation and modification times",
+			ts.Record())
+	}
+	ts.SetStatus(Doing)
+	started := ts.Started()
+	if started.IsZero() {
+		t.Fatalf("expected start time")
+	}
+	ts.started = started.Add(-time.Hour)
+	ts.SetStatus(Todo)
+	ts.SetStatus(Doing)
+	if !ts.Started().Equal(started.Add(-time.Hour)) {
+		t.Fatalf("got %v, want the first start time kept", ts.Started())
+	}
+	ts.SetStatus(Done)
+	if ts.Completed().IsZero() {
+		t.Fatalf("expected completion time")
+	}
+	ts.SetStatus(Todo)
+	if !ts.Completed().IsZero() {
+		t.Fatalf("got %v, want no completion time", ts.Completed())
+	}
+	done, _ := NewTask("done", "", false, Medium, Done)
+	if done.Completed().IsZero() {
+		t.Fatalf("expected completion time for a task created done")
+	}
+}
+
+func TestRescheduleClearsCompletionTime(t *testing.T) {
+	ts, _ := NewTask("water plants", "", true, Medium, Doing)
+	started := ts.Started()
+	ts.SetStatus(Done)
+	if !ts.Reschedule(time.Now()) {
+		t.Fatalf("expected periodic task to be rescheduled")
+	}
+	if ts.Status() != Todo || !ts.Completed().IsZero() {
+		t.Fatalf("got status %v completed %v, want to do without completion",
+			ts.Status(), ts.Completed())
+	}
+	if !ts.Started().Equal(started) {
+		t.Fatalf("got %v, want the start time kept", ts.Started())
+	}
+	if ts.IsArchivable(time.Time{}) {
+		t.Fatalf("rescheduled task archivable")
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	// a thursday
+	now := time.Date(2026, time.October, 15, 10, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time {
+		return time.Date(2026, time.October, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"today", day(15), day(16)},
+		{"yesterday", day(14), day(15)},
+		{"this-week", day(12), day(19)},
+		{"last-week", day(5), day(12)},
+		{"this-month", day(1), day(32)},
+		{"last-month", day(1).AddDate(0, -1, 0), day(1)},
+		{"3d", now.AddDate(0, 0, -3), now.Add(time.Second)},
+	}
+	for _, test := range tests {
+		start, end, ok := timeRange(test.name, now)
+		if !ok || !start.Equal(test.start) || !end.Equal(test.end) {
+			t.Fatalf("%s: got %v to %v, want %v to %v", test.name, start, end,
+				test.start, test.end)
+		}
+	}
+	for _, name := range []string{"", "week", "0d", "+3d", "d", "-2d"} {
+		if _, _, ok := timeRange(name, now); ok {
+			t.Fatalf("%q: expected invalid range", name)
+		}
+	}
+}
+
+func TestTimeFilters(t *testing.T) {
+	now := time.Date(2026, time.October, 15, 10, 0, 0, 0, time.UTC)
+	lastWeek, _ := NewDefault("last week")
+	lastWeek.completed = time.Date(2026, time.October, 7, 0, 0, 0, 0, time.UTC)
+	thisWeek, _ := NewDefault("this week")
+	thisWeek.completed = time.Date(2026, time.October, 13, 0, 0, 0, 0, time.UTC)
+	open, _ := NewDefault("open")
+	tasks := []*Task{lastWeek, thisWeek, open}
+	got, err := filterTasksAt(tasks, []string{"completed:last-week"}, now)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(got, []*Task{lastWeek}) {
+		t.Fatalf("got %v, want [last week]", sortedTitles(got))
+	}
+	if !IsValidTimeFilter("created:7d") || IsValidTimeFilter("due:today") ||
+		IsValidTimeFilter("completed:soon") {
+		t.Fatalf("unexpected time filter validity")
+	}
+	q, err := ParseQuery("completed>=2026-10-10 or completed=none", now)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if got = q.Filter(tasks); !slices.Equal(got, []*Task{thisWeek, open}) {
+		t.Fatalf("got %v, want [this week open]", sortedTitles(got))
+	}
+}
+
+func TestSortTasksByCompletion(t *testing.T) {
+	first, _ := NewDefault("first")
+	first.completed = time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	second, _ := NewDefault("second")
+	second.completed = time.Date(2026, time.October, 2, 0, 0, 0, 0, time.UTC)
+	open, _ := NewDefault("open")
+	tasks := []*Task{open, first, second}
+	keys, err := ParseSortKeys("completed:desc")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	SortTasks(tasks, keys)
+	if want := []*Task{second, first, open}; !slices.Equal(tasks, want) {
+		t.Fatalf("got %v, want %v", sortedTitles(tasks), sortedTitles(want))
+	}
+}
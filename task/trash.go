@@ -0,0 +1,136 @@
+package task
+
+import (
+	"slices"
+	"time"
+)
+
+// The trash the tasks removed by Remove, and by UndoLast and RedoLast, are
+// moved to. If it is nil, removed tasks are deleted.
+var DefaultTrash *Trash = nil
+
+// A Trash keeps removed tasks in a store of its own, with the time they were
+// removed, until they are restored or the trash is emptied. Trashed tasks are
+// not part of the store they were removed from, so that they are neither
+// listed nor looked up.
+type Trash struct {
+	store Store // the store of the trashed tasks
+}
+
+// Returns a trash keeping the trashed tasks in the given store.
+func NewTrash(store Store) *Trash {
+	return &Trash{store: store}
+}
+
+// Returns the time the task was moved to the trash, zero if it is not
+// trashed.
+func (t *Task) Deleted() time.Time {
+	return t.deleted
+}
+
+// Moves the given task, removed from its store, to the trash.
+func (tr *Trash) Put(t *Task) error {
+	trashed := *t
+	trashed.deleted = now()
+	return tr.store.Save(&trashed)
+}
+
+// Removes for good the task of given full uuid from the trash, if it is
+// there.
+func (tr *Trash) Discard(uuid string) error {
+	return tr.store.Remove(uuid)
+}
+
+// Returns the trashed tasks, the latest trashed first.
+func (tr *Trash) Tasks() ([]*Task, error) {
+	tasks, err := tr.store.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(tasks, func(a, b *Task) int {
+		return b.deleted.Compare(a.deleted)
+	})
+	return tasks, nil
+}
+
+// Moves the only trashed task whose uuid has the given prefix back to the
+// given store, and returns it. Its relations to tasks missing from the store
+// are dropped, see dropMissingRelations. Returns ErrTaskNotFound if there is
+// no such task, ErrUuidNotUnique if there are several and ErrTaskExists if the
+// store holds a task of same uuid, which is left unchanged.
+func (tr *Trash) Restore(prefix string, store Store) (*Task, error) {
+	t, err := LoadUnique(tr.store, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if exists, err := store.Exists(t.uuid); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, ErrTaskExists
+	}
+	t.deleted = time.Time{}
+	if err = dropMissingRelations(store, t, nil); err != nil {
+		return nil, err
+	}
+	// the task is saved before being removed from the trash, so that an
+	// interruption leaves it in both rather than in none
+	if err = store.Save(t); err != nil {
+		return nil, err
+	}
+	return t, tr.store.Remove(t.uuid)
+}
+
+// Removes for good the tasks trashed before the given time, every task if it
+// is zero, and returns their number.
+func (tr *Trash) Empty(before time.Time) (int, error) {
+	tasks, err := tr.store.LoadAll()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	err = Batch(tr.store, func(s Store) error {
+		for _, t := range tasks {
+			if before.IsZero() || t.deleted.Before(before) {
+				if err := s.Remove(t.uuid); err != nil {
+					return err
+				}
+				removed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// Brings the trash in line with the tasks of the given operation restored to
+// their states before it if before is true, after it otherwise: the tasks
+// restored are no longer trashed, and those removed by the restoration are
+// trashed in the state they had.
+func (tr *Trash) reconcile(op *Operation, before bool) error {
+	for _, change := range op.Tasks {
+		state, other := change.After, change.Before
+		if before {
+			state, other = other, state
+		}
+		if state != nil {
+			if err := tr.Discard(change.Uuid); err != nil {
+				return err
+			}
+			continue
+		}
+		if other == nil {
+			continue
+		}
+		t, _, err := decodeTask(other)
+		if err != nil {
+			return err
+		}
+		if err = tr.Put(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package task
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemoveMovesTaskToDefaultTrash(t *testing.T) {
+	store := NewMemoryStore()
+	trash := NewTrash(NewMemoryStore())
+	DefaultTrash = trash
+	defer func() { DefaultTrash = nil }()
+	removed, _ := NewDefault("removed")
+	store.Save(removed)
+	if err := removeIn(store, removed.Uuid()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if unique, _ := existsAndIsUniqueIn(store, removed.Uuid()); unique {
+		t.Fatalf("removed task still in the store")
+	}
+	trashed, err := trash.Tasks()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(trashed) != 1 || trashed[0].Uuid() != removed.Uuid() {
+		t.Fatalf("got %v, want the removed task", trashed)
+	}
+	if trashed[0].Deleted().IsZero() {
+		t.Fatalf("trashed task has no deletion time")
+	}
+}
+
+func TestRestoreDropsMissingRelations(t *testing.T) {
+	store := NewMemoryStore()
+	trash := NewTrash(NewMemoryStore())
+	kept, _ := NewDefault("kept")
+	gone, _ := NewDefault("gone")
+	store.Save(kept)
+	trashed, _ := NewDefault("trashed")
+	trashed.SetParent(gone.Uuid())
+	trashed.AddDependency(kept.Uuid())
+	trashed.AddDependency(gone.Uuid())
+	if err := trash.Put(trashed); err != nil {
+		t.Fatalf(err.Error())
+	}
+	restored, err := trash.Restore(trashed.Uuid()[:8], store)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !restored.Deleted().IsZero() {
+		t.Fatalf("restored task still has a deletion time")
+	}
+	if restored.Parent() != "" {
+		t.Fatalf("got parent %v, want none", restored.Parent())
+	}
+	if deps := restored.Dependencies(); len(deps) != 1 || deps[0] != kept.Uuid() {
+		t.Fatalf("got %v, want [%v]", deps, kept.Uuid())
+	}
+	if unique, _ := existsAndIsUniqueIn(store, trashed.Uuid()); !unique {
+		t.Fatalf("restored task not in the store")
+	}
+	if tasks, _ := trash.Tasks(); len(tasks) != 0 {
+		t.Fatalf("got %d trashed tasks, want 0", len(tasks))
+	}
+	if _, err = trash.Restore(trashed.Uuid(), store); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrTaskNotFound)
+	}
+}
+
+func TestRestoreRefusesUuidCollisions(t *testing.T) {
+	store := NewMemoryStore()
+	trash := NewTrash(NewMemoryStore())
+	trashed, _ := NewDefault("trashed")
+	trash.Put(trashed)
+	live := *trashed
+	live.title = "live"
+	store.Save(&live)
+	_, err := trash.Restore(trashed.Uuid(), store)
+	if !errors.Is(err, ErrTaskExists) {
+		t.Fatalf("got %v, want %v", err, ErrTaskExists)
+	}
+	if kept, _ := LoadUnique(store, trashed.Uuid()); kept.Title() != "live" {
+		t.Fatalf("got %v, want the live task kept", kept.Title())
+	}
+	if tasks, _ := trash.Tasks(); len(tasks) != 1 {
+		t.Fatalf("got %d trashed tasks, want 1", len(tasks))
+	}
+}
+
+func TestEmptyRemovesOlderTasks(t *testing.T) {
+	trash := NewTrash(NewMemoryStore())
+	old, _ := NewDefault("old")
+	recent, _ := NewDefault("recent")
+	trash.Put(old)
+	trash.Put(recent)
+	tasks, _ := trash.Tasks()
+	// back date one of the tasks
+	tasks[1].deleted = tasks[1].deleted.AddDate(0, 0, -40)
+	trash.store.Save(tasks[1])
+	removed, err := trash.Empty(now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if removed != 1 {
+		t.Fatalf("got %v, want %v", removed, 1)
+	}
+	if removed, _ = trash.Empty(time.Time{}); removed != 1 {
+		t.Fatalf("got %v, want %v", removed, 1)
+	}
+}
+
+func TestUndoAndRedoOfRemoveUpdateTrash(t *testing.T) {
+	store := NewMemoryStore()
+	trash := NewTrash(NewMemoryStore())
+	DefaultTrash = trash
+	defer func() { DefaultTrash = nil }()
+	removed, _ := NewDefault("removed")
+	store.Save(removed)
+	j, err := OpenUndoJournal(filepath.Join(t.TempDir(), "undo"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	recordOperation(t, store, j, func(s Store) error {
+		return removeIn(s, removed.Uuid())
+	})
+	if _, err = j.UndoLast(store); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if tasks, _ := trash.Tasks(); len(tasks) != 0 {
+		t.Fatalf("got %d trashed tasks after undo, want 0", len(tasks))
+	}
+	if _, err = j.RedoLast(store); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if tasks, _ := trash.Tasks(); len(tasks) != 1 {
+		t.Fatalf("got %d trashed tasks after redo, want 1", len(tasks))
+	}
+}
@@ -0,0 +1,246 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// The number of operations kept to be undone at most, the oldest being
+// dropped first.
+const UndoMaxOperations = 100
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// An Operation is a change of tasks made by a command. It is undone by
+// restoring the states of its tasks before the change, and redone by
+// restoring their states after it.
+type Operation struct {
+	Time    time.Time       `json:"time"`
+	Command string          `json:"command"` // the command that made the change
+	Tasks   []OperationTask `json:"tasks"`
+}
+
+// An OperationTask is the change of a task made by an operation.
+type OperationTask struct {
+	Uuid   string `json:"uuid"`
+	Before []byte `json:"before"` // the encoded task, nil if it did not exist
+	After  []byte `json:"after"`  // the encoded task, nil if it was removed
+}
+
+// An OperationStore is a store keeping the state of every task it changes
+// before its first change, to build the operation of its changes.
+type OperationStore struct {
+	Store
+	state *operationState // shared with the stores of the batches
+}
+
+// The states of the tasks changed by an OperationStore.
+type operationState struct {
+	before map[string][]byte // the encoded tasks by uuid, nil if missing
+	uuids  []string          // the uuids of the changed tasks, in order
+}
+
+// Returns a store saving its tasks in the given store and keeping the states
+// of the tasks it changes.
+func NewOperationStore(store Store) *OperationStore {
+	return &OperationStore{Store: store,
+		state: &operationState{before: make(map[string][]byte)}}
+}
+
+// Returns the encoded task of given full uuid in the given store, nil if
+// there is none.
+func encodedTask(store Store, uuid string) ([]byte, error) {
+	tasks, err := store.Load(uuid)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		if t.uuid == uuid {
+			return t.encode(), nil
+		}
+	}
+	return nil, nil
+}
+
+// Keeps the state of the task of given uuid if it was not changed yet.
+func (s *OperationStore) keep(uuid string) error {
+	if _, ok := s.state.before[uuid]; ok {
+		return nil
+	}
+	before, err := encodedTask(s.Store, uuid)
+	if err != nil {
+		return err
+	}
+	s.state.before[uuid] = before
+	s.state.uuids = append(s.state.uuids, uuid)
+	return nil
+}
+
+func (s *OperationStore) Save(t *Task) error {
+	if err := s.keep(t.uuid); err != nil {
+		return err
+	}
+	return s.Store.Save(t)
+}
+
+func (s *OperationStore) Remove(uuid string) error {
+	if err := s.keep(uuid); err != nil {
+		return err
+	}
+	return s.Store.Remove(uuid)
+}
+
+// Calls fn with a store whose changes are applied at once if the wrapped
+// store is a Batcher.
+func (s *OperationStore) Batch(fn func(Store) error) error {
+	return Batch(s.Store, func(inner Store) error {
+		return fn(&OperationStore{Store: inner, state: s.state})
+	})
+}
+
+// Returns the tasks matching the given filters of FilterTasks at the given
+// time, loaded by the wrapped store if it is a FilterStore.
+func (s *OperationStore) LoadFiltered(filters []string, now time.Time) ([]*Task,
+	error) {
+	return loadFilteredAt(s.Store, filters, now)
+}
+
+// Closes the wrapped store if it is an io.Closer.
+func (s *OperationStore) Close() error {
+	return closeStore(s.Store)
+}
+
+// Returns the operation of given command changing the tasks of the store
+// from their kept states to their current states, nil if no task changed.
+func (s *OperationStore) Operation(command string) (*Operation, error) {
+	op := &Operation{Time: now(), Command: command}
+	for _, uuid := range s.state.uuids {
+		after, err := encodedTask(s.Store, uuid)
+		if err != nil {
+			return nil, err
+		}
+		before := s.state.before[uuid]
+		if !bytes.Equal(before, after) {
+			op.Tasks = append(op.Tasks, OperationTask{uuid, before, after})
+		}
+	}
+	if len(op.Tasks) == 0 {
+		return nil, nil
+	}
+	return op, nil
+}
+
+// An UndoJournal keeps in a file the operations that can be undone, and those
+// that were undone and can be redone.
+type UndoJournal struct {
+	path string       // the path of the journal file
+	Undo []*Operation `json:"undo"` // the operations to undo, the latest last
+	Redo []*Operation `json:"redo"` // the undone operations, the latest last
+}
+
+// Opens the undo journal of given file path, created on the first change.
+func OpenUndoJournal(path string) (*UndoJournal, error) {
+	j := &UndoJournal{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Writes the journal to its file.
+func (j *UndoJournal) save() error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(j.path, data, 0644)
+}
+
+// Adds the given operation to the operations to undo, dropping the oldest if
+// there are more than UndoMaxOperations, and forgets the operations to redo.
+func (j *UndoJournal) Record(op *Operation) error {
+	j.Undo = append(j.Undo, op)
+	if len(j.Undo) > UndoMaxOperations {
+		j.Undo = j.Undo[len(j.Undo)-UndoMaxOperations:]
+	}
+	j.Redo = nil
+	return j.save()
+}
+
+// Sets the tasks of the given operation in the given store to their states
+// before the operation if before is true, after it otherwise, at once if the
+// store is a Batcher. The removed tasks are moved to DefaultTrash, if set, and
+// the tasks restored are taken out of it.
+func restoreOperation(store Store, op *Operation, before bool) error {
+	err := Batch(store, func(s Store) error {
+		for _, change := range op.Tasks {
+			state := change.After
+			if before {
+				state = change.Before
+			}
+			if state == nil {
+				if err := s.Remove(change.Uuid); err != nil {
+					return err
+				}
+				continue
+			}
+			t, _, err := decodeTask(state)
+			if err != nil {
+				return err
+			}
+			if err = s.Save(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || DefaultTrash == nil {
+		return err
+	}
+	return DefaultTrash.reconcile(op, before)
+}
+
+// Restores in the given store the tasks of the latest operation to undo to
+// their states before it, and returns the operation, then to redo. Returns
+// ErrNothingToUndo if there is no operation to undo.
+func (j *UndoJournal) UndoLast(store Store) (*Operation, error) {
+	if len(j.Undo) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	op := j.Undo[len(j.Undo)-1]
+	if err := restoreOperation(store, op, true); err != nil {
+		return nil, err
+	}
+	j.Undo = j.Undo[:len(j.Undo)-1]
+	j.Redo = append(j.Redo, op)
+	return op, j.save()
+}
+
+// Restores in the given store the tasks of the latest undone operation to
+// their states after it, and returns the operation, then to undo again.
+// Returns ErrNothingToRedo if there is no operation to redo.
+func (j *UndoJournal) RedoLast(store Store) (*Operation, error) {
+	if len(j.Redo) == 0 {
+		return nil, ErrNothingToRedo
+	}
+	op := j.Redo[len(j.Redo)-1]
+	if err := restoreOperation(store, op, false); err != nil {
+		return nil, err
+	}
+	j.Redo = j.Redo[:len(j.Redo)-1]
+	j.Undo = append(j.Undo, op)
+	return op, j.save()
+}
@@ -0,0 +1,130 @@
+package task
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Runs fn on an operation store wrapping the given store, and records the
+// operation of its changes in the given journal.
+func recordOperation(t *testing.T, store Store, j *UndoJournal,
+	fn func(Store) error) {
+	ops := NewOperationStore(store)
+	if err := fn(ops); err != nil {
+		t.Fatalf(err.Error())
+	}
+	op, err := ops.Operation("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if op == nil {
+		t.Fatalf("expected an operation")
+	}
+	if err = j.Record(op); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestUndoAndRedoRestoreTasks(t *testing.T) {
+	store := NewMemoryStore()
+	kept, _ := NewDefault("kept")
+	removed, _ := NewDefault("removed")
+	store.Save(kept)
+	store.Save(removed)
+	path := filepath.Join(t.TempDir(), "undo")
+	j, err := OpenUndoJournal(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	created, _ := NewDefault("created")
+	recordOperation(t, store, j, func(s Store) error {
+		return Batch(s, func(s Store) error {
+			kept.SetStatus(Done)
+			s.Save(kept)
+			s.Save(created)
+			return s.Remove(removed.Uuid())
+		})
+	})
+	// the journal is read again, as by another invocation
+	if j, err = OpenUndoJournal(path); err != nil {
+		t.Fatalf(err.Error())
+	}
+	op, err := j.UndoLast(store)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(op.Tasks) != 3 || op.Command != "test" {
+		t.Fatalf("got %d tasks, want 3", len(op.Tasks))
+	}
+	tasks, _ := store.LoadAll()
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+	restored, _ := LoadUnique(store, kept.Uuid())
+	if restored.Status() != Todo {
+		t.Fatalf("got status %d, want %d", restored.Status(), Todo)
+	}
+	if exists, _ := store.Exists(removed.Uuid()); !exists {
+		t.Fatalf("expected removed task restored")
+	}
+	if exists, _ := store.Exists(created.Uuid()); exists {
+		t.Fatalf("expected created task removed")
+	}
+	if _, err = j.RedoLast(store); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if exists, _ := store.Exists(removed.Uuid()); exists {
+		t.Fatalf("expected removed task removed again")
+	}
+	if _, err = j.RedoLast(store); err != ErrNothingToRedo {
+		t.Fatalf("got %v, want %v", err, ErrNothingToRedo)
+	}
+}
+
+func TestRecordForgetsRedoAndDropsOldestOperations(t *testing.T) {
+	store := NewMemoryStore()
+	j, err := OpenUndoJournal(filepath.Join(t.TempDir(), "undo"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i := 0; i < UndoMaxOperations+1; i++ {
+		ts, _ := NewDefault("task")
+		recordOperation(t, store, j, func(s Store) error {
+			return s.Save(ts)
+		})
+	}
+	if len(j.Undo) != UndoMaxOperations {
+		t.Fatalf("got %d operations, want %d", len(j.Undo), UndoMaxOperations)
+	}
+	if _, err = j.UndoLast(store); err != nil {
+		t.Fatalf(err.Error())
+	}
+	ts, _ := NewDefault("task")
+	recordOperation(t, store, j, func(s Store) error {
+		return s.Save(ts)
+	})
+	if len(j.Redo) != 0 {
+		t.Fatalf("got %d operations to redo, want none", len(j.Redo))
+	}
+}
+
+func TestOperationWithoutChangeIsNil(t *testing.T) {
+	store := NewMemoryStore()
+	ts, _ := NewDefault("task")
+	store.Save(ts)
+	ops := NewOperationStore(store)
+	if err := ops.Save(ts); err != nil {
+		t.Fatalf(err.Error())
+	}
+	op, err := ops.Operation("test")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if op != nil {
+		t.Fatalf("got %v, want no operation", op)
+	}
+	j, _ := OpenUndoJournal(filepath.Join(t.TempDir(), "undo"))
+	if _, err = j.UndoLast(store); err != ErrNothingToUndo {
+		t.Fatalf("got %v, want %v", err, ErrNothingToUndo)
+	}
+}
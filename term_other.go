@@ -0,0 +1,10 @@
+//go:build !(linux || darwin)
+
+package main
+
+import "os"
+
+// Returns 0 as the size of terminals is not known on this system.
+func terminalColumns(f *os.File) int {
+	return 0
+}